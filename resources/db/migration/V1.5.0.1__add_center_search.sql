@@ -0,0 +1,33 @@
+create extension if not exists unaccent;
+
+create text search configuration german_unaccent (copy = german);
+
+alter text search configuration german_unaccent
+    alter mapping for hword, hword_part, word with unaccent, german_stem;
+
+alter table centers
+    add search_vector tsvector;
+
+create or replace function centers_search_vector_update() returns trigger as
+$BODY$
+begin
+    new.search_vector :=
+                setweight(to_tsvector('german_unaccent', coalesce(new.name, '')), 'A') ||
+                setweight(to_tsvector('german_unaccent', coalesce(new.operator_name, '')), 'B') ||
+                setweight(to_tsvector('german_unaccent', coalesce(new.address, '') || ' ' ||
+                                                         coalesce(new.zip, '') || ' ' ||
+                                                         coalesce(new.region, '')), 'C');
+    return new;
+end
+$BODY$ language plpgsql;
+
+create trigger centers_search_vector_trigger
+    before insert or update of name, operator_name, address, zip, region
+    on centers
+    for each row
+execute procedure centers_search_vector_update();
+
+update centers set name = name;
+
+create index centers_search_vector_index
+    on centers using gin (search_vector);
@@ -0,0 +1,2 @@
+alter table centers
+    add column opening_schedule jsonb;
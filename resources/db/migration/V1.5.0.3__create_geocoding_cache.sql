@@ -0,0 +1,10 @@
+create table geocoding_cache_entries
+(
+    address varchar(512) not null primary key,
+    status  varchar(32)  not null,
+    result  jsonb,
+    created timestamp    not null
+);
+
+create index geocoding_cache_entries_created_index
+    on geocoding_cache_entries (created);
@@ -0,0 +1,13 @@
+create table geocoding_jobs
+(
+    center_uuid  varchar(36) not null primary key
+        references centers on delete cascade on update cascade,
+    attempts     integer     not null default 0,
+    next_attempt timestamp   not null,
+    locked_until timestamp,
+    last_error   varchar(512),
+    created      timestamp   not null
+);
+
+create index geocoding_jobs_next_attempt_index
+    on geocoding_jobs (next_attempt);
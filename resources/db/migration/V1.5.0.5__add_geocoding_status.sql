@@ -0,0 +1,18 @@
+alter table centers
+    add column geocoding_status        varchar(16),
+    add column geocoding_location_type varchar(16),
+    add column geocoding_address       text,
+    add column geocoding_provider      varchar(32);
+
+update centers
+set geocoding_status = case
+                           when message like 'Geocoding: too many results%' then 'ambiguous'
+                           when message like 'Geocoding: no results%' then 'failed'
+                           when exists(select 1 from geocoding_jobs j where j.center_uuid = centers.uuid) then 'pending'
+                           when fixed then 'manual'
+                           when latitude = 0 and longitude = 0 then 'failed'
+                           else 'ok'
+    end;
+
+create index centers_geocoding_status_index
+    on centers (operator_uuid, geocoding_status);
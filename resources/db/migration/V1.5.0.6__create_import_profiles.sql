@@ -0,0 +1,14 @@
+create table import_profiles
+(
+    uuid         varchar(36)    not null primary key,
+    name         varchar(64)    not null unique,
+    delimiter    varchar(1)     not null,
+    header_rows  integer        not null,
+    date_formats varchar(32)[]  not null,
+    true_values  varchar(32)[]  not null,
+    columns      jsonb          not null
+);
+
+alter table operators
+    add column import_profile_uuid varchar(36)
+        references import_profiles on delete set null on update cascade;
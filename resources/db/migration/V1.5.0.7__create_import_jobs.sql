@@ -0,0 +1,27 @@
+create table import_jobs
+(
+    uuid          varchar(36)  not null primary key,
+    operator_uuid varchar(36)  not null
+        references operators on delete cascade on update cascade,
+    status        varchar(16)  not null,
+    delete_all    boolean      not null default false,
+    partial       boolean      not null default false,
+    allow_dcc     boolean      not null default false,
+    centers       jsonb        not null,
+    total         integer      not null default 0,
+    processed     integer      not null default 0,
+    succeeded     integer      not null default 0,
+    failed        integer      not null default 0,
+    errors        jsonb        not null default '[]',
+    error         varchar(512),
+    created       timestamp    not null,
+    started       timestamp,
+    finished      timestamp,
+    locked_until  timestamp
+);
+
+create index import_jobs_operator_index
+    on import_jobs (operator_uuid, created);
+
+create index import_jobs_status_index
+    on import_jobs (status);
@@ -0,0 +1,23 @@
+alter table import_jobs
+    add column submitted_by varchar(255) not null default '';
+
+create table import_records
+(
+    uuid            varchar(36)   not null primary key,
+    operator_uuid   varchar(36)   not null
+        references operators on delete cascade on update cascade,
+    imported_by     varchar(255)  not null,
+    imported        timestamp     not null,
+    delete_all      boolean       not null default false,
+    partial         boolean       not null default false,
+    created         integer       not null default 0,
+    updated         integer       not null default 0,
+    deleted         integer       not null default 0,
+    created_centers varchar(36)[] not null,
+    snapshot        jsonb         not null,
+    rolled_back     timestamp,
+    rolled_back_by  varchar(255)
+);
+
+create index import_records_operator_index
+    on import_records (operator_uuid, imported);
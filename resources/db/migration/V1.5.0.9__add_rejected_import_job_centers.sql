@@ -0,0 +1,2 @@
+alter table import_jobs
+    add column rejected jsonb not null default '[]';
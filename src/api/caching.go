@@ -0,0 +1,42 @@
+/*
+ *   Corona-Warn-App / cwa-map-backend
+ *
+ *   (C) 2020, T-Systems International GmbH
+ *
+ *   Deutsche Telekom AG and all other contributors /
+ *   copyright owners license this file to you under the Apache
+ *   License, Version 2.0 (the "License"); you may not use this
+ *   file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing,
+ *   software distributed under the License is distributed on an
+ *   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *   KIND, either express or implied.  See the License for the
+ *   specific language governing permissions and limitations
+ *   under the License.
+ */
+
+package api
+
+import (
+	"com.t-systems-mms.cwa/core/api"
+	"net/http"
+	"time"
+)
+
+// CachingConfig contains the max ages (in seconds) of the public endpoints for the Cache-Control headers
+type CachingConfig struct {
+	SearchMaxAge int
+	TilesMaxAge  int
+	AssetsMaxAge int
+}
+
+// cacheControl sets the Cache-Control header of the route. The responses of the centers don't have a Last-Modified
+// header, as they depend on the current time (e.g. opening hours, enter dates and the age of the centers),
+// so conditional requests only rely on the ETag of the content.
+func cacheControl(maxAge int) func(http.Handler) http.Handler {
+	return api.CacheControl(time.Duration(maxAge) * time.Second)
+}
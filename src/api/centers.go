@@ -22,16 +22,20 @@
 package api
 
 import (
+	"bytes"
 	"com.t-systems-mms.cwa/api/model"
+	"com.t-systems-mms.cwa/core"
 	"com.t-systems-mms.cwa/core/api"
+	"com.t-systems-mms.cwa/core/mvt"
 	"com.t-systems-mms.cwa/core/security"
 	"com.t-systems-mms.cwa/core/util"
 	"com.t-systems-mms.cwa/domain"
 	"com.t-systems-mms.cwa/external/geocoding"
 	"com.t-systems-mms.cwa/repositories"
 	"com.t-systems-mms.cwa/services"
-	"context"
 	"encoding/csv"
+	"encoding/json"
+	"errors"
 	"github.com/go-chi/chi"
 	"github.com/go-chi/jwtauth"
 	"github.com/go-playground/validator"
@@ -39,9 +43,11 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
+	"io"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 )
 
 var (
@@ -49,6 +55,27 @@ var (
 	ErrInvalidParameters = api.HandlerError{Status: http.StatusBadRequest, Err: "invalid parameters"}
 )
 
+const (
+	defaultNearbyRadius = 10.0
+	maxNearbyRadius     = 100.0
+	defaultNearbyLimit  = 20
+	maxNearbyLimit      = 200
+
+	// minSearchQueryLength is the minimum length of full text search queries
+	minSearchQueryLength = 2
+	maxSearchPageSize    = 100
+
+	// maxTileFeatures is the maximum count of centers encoded into a single vector tile
+	maxTileFeatures = 4096
+
+	// tileBuffer is the size of the area around a tile (as fraction of the tile size), which is included
+	// in the tile, so markers at the edges are not cut off
+	tileBuffer = 1.0 / 16
+
+	// maxImportSize is the maximum size (in bytes) of uploaded csv and spreadsheet files
+	maxImportSize = 20 << 20
+)
+
 var (
 	findCentersRequestsCounter = promauto.NewCounter(prometheus.CounterOpts{
 		Name: "cwa_map_find_centers_request_count",
@@ -65,17 +92,43 @@ var (
 		Help: "The total count of requests returning no centers",
 	})
 
+	findClustersRequestsCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "cwa_map_find_clusters_request_count",
+		Help: "The total count of find clusters requests",
+	})
+
+	searchCentersRequestsCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "cwa_map_search_centers_request_count",
+		Help: "The total count of full text center search requests",
+	})
+
+	findTilesRequestsCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "cwa_map_find_tiles_request_count",
+		Help: "The total count of vector tile requests",
+	})
+
 	geocodeRequestsCounter = promauto.NewCounter(prometheus.CounterOpts{
 		Name: "cwa_map_geocode_request_count",
 		Help: "The total count of geocode requests",
 	})
+
+	reverseGeocodeRequestsCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "cwa_map_reverse_geocode_request_count",
+		Help: "The total count of reverse geocode requests",
+	})
 )
 
 type Centers struct {
 	chi.Router
 	centersService    services.Centers
 	geocoder          geocoding.Geocoder
+	geocodingCache    repositories.GeocodingCache
+	plausibility      services.Plausibility
+	importProfiles    services.ImportProfiles
+	importJobs        services.ImportJobs
+	importRecords     repositories.ImportRecords
 	operatorsService  services.Operators
+	operators         repositories.Operators
 	centersRepository repositories.Centers
 	bugReportsService services.BugReports
 	validate          *validator.Validate
@@ -83,7 +136,9 @@ type Centers struct {
 
 func NewCentersAPI(centersService services.Centers, centersRepository repositories.Centers,
 	bugReportsService services.BugReports,
-	operatorsService services.Operators, geocoder geocoding.Geocoder, auth *jwtauth.JWTAuth) *Centers {
+	operatorsService services.Operators, operators repositories.Operators, geocoder geocoding.Geocoder, geocodingCache repositories.GeocodingCache,
+	plausibility services.Plausibility, importProfiles services.ImportProfiles, importJobs services.ImportJobs,
+	importRecords repositories.ImportRecords, caching CachingConfig, auth *jwtauth.JWTAuth) *Centers {
 	validate := validator.New()
 	validate.RegisterTagNameFunc(util.JsonTagNameFunc)
 
@@ -92,14 +147,28 @@ func NewCentersAPI(centersService services.Centers, centersRepository repositori
 		centersService:    centersService,
 		centersRepository: centersRepository,
 		operatorsService:  operatorsService,
+		operators:         operators,
 		geocoder:          geocoder,
+		geocodingCache:    geocodingCache,
+		plausibility:      plausibility,
+		importProfiles:    importProfiles,
+		importJobs:        importJobs,
+		importRecords:     importRecords,
 		bugReportsService: bugReportsService,
 		validate:          validate,
 	}
 
 	// public endpoints
-	centers.Get("/", api.Handle(centers.findCenters))
-	centers.Get("/bounds", api.Handle(centers.geocode))
+	centers.Group(func(r chi.Router) {
+		r.Use(cacheControl(caching.SearchMaxAge))
+		r.Get("/", api.HandleCacheable(centers.findCenters))
+		r.Get("/nearby", api.HandleCacheable(centers.findNearbyCenters))
+		r.Get("/clusters", api.HandleCacheable(centers.findClusters))
+		r.Get("/search", api.HandleCacheable(centers.searchCenters))
+		r.Get("/bounds", api.HandleCacheable(centers.geocode))
+		r.Get("/address", api.HandleCacheable(centers.reverseGeocode))
+	})
+	centers.With(cacheControl(caching.TilesMaxAge)).Get("/tiles/{z}/{x}/{y}.mvt", centers.getTile)
 	centers.Post("/{uuid}/report", api.Handle(centers.createBugReport))
 
 	centers.Group(func(r chi.Router) {
@@ -107,8 +176,14 @@ func NewCentersAPI(centersService services.Centers, centersRepository repositori
 		r.Use(jwtauth.Authenticator)
 
 		r.Get("/all", api.Handle(centers.getAllCenters))
+		r.Get("/geocoding-issues", api.Handle(centers.getGeocodingIssues))
 		r.Post("/csv", api.Handle(centers.prepareCSVImport))
 		r.Post("/", api.Handle(centers.importCenters))
+		r.Get("/imports", api.Handle(centers.getImportJobs))
+		r.Post("/imports", api.Handle(centers.submitImportJob))
+		r.Get("/imports/{uuid}", api.Handle(centers.getImportJob))
+		r.Get("/imports/history", api.Handle(centers.getImportHistory))
+		r.Post("/imports/history/rollback", api.Handle(centers.rollbackLastImport))
 		r.Put("/{uuid}", api.Handle(centers.updateCenter))
 
 		// get centers
@@ -122,8 +197,13 @@ func NewCentersAPI(centersService services.Centers, centersRepository repositori
 
 		r.Route("/admin", func(r chi.Router) {
 			r.Use(api.RequireRole(security.RoleAdmin))
-			r.Get("/csv", centers.exportCentersAsCSV)
+			r.Get("/csv", centers.exportCenters)
+			r.Get("/quality", api.Handle(centers.getQualityReport))
+			r.Get("/imports/history/{operator}", api.Handle(centers.getImportHistory))
+			r.Post("/imports/history/{operator}/rollback", api.Handle(centers.rollbackLastImport))
 			r.Post("/geocode", api.Handle(centers.geocodeAllCenters))
+			r.Post("/opening-hours", api.Handle(centers.updateOpeningSchedules))
+			r.Delete("/geocoding-cache", api.Handle(centers.purgeGeocodingCache))
 		})
 	})
 	return centers
@@ -148,8 +228,28 @@ func (c *Centers) geocode(_ http.ResponseWriter, r *http.Request) (interface{},
 	return nil, ErrInvalidParameters
 }
 
-func (c *Centers) findCenters(_ http.ResponseWriter, r *http.Request) (interface{}, error) {
+// reverseGeocode returns the address of the coordinates given by the lat and lng parameters
+func (c *Centers) reverseGeocode(_ http.ResponseWriter, r *http.Request) (interface{}, error) {
+	reverseGeocodeRequestsCounter.Inc()
+	location, hasLocation, err := c.getLocationParameter(r)
+	if err != nil || !hasLocation {
+		return nil, ErrInvalidParameters
+	}
+
+	result, err := c.geocoder.ReverseGeocode(r.Context(), location)
+	if err != nil {
+		if err == geocoding.ErrNoResult {
+			return nil, api.HandlerError{Status: http.StatusNotFound, Err: err.Error()}
+		}
+		return nil, err
+	}
+	return model.ReverseGeocodeResultDTO{}.MapFromModel(&result), nil
+}
+
+func (c *Centers) findCenters(w http.ResponseWriter, r *http.Request) (interface{}, error) {
 	findCentersRequestsCounter.Inc()
+	// the response is cached publicly, but its format depends on the Accept header
+	w.Header().Add("Vary", "Accept")
 	if bounds, hasBounds, err := c.getBoundsParameter(r); hasBounds && err == nil {
 		searchParameters := c.getSearchParameters(r)
 		centers, err := c.centersRepository.FindByBounds(r.Context(), bounds, searchParameters, 200)
@@ -164,6 +264,11 @@ func (c *Centers) findCenters(_ http.ResponseWriter, r *http.Request) (interface
 		}
 		deliveredCentersCounter.Add(float64(centersCount))
 
+		if c.isGeoJSONRequested(r) {
+			w.Header().Set("Content-Type", model.GeoJSONContentType)
+			return model.MapToCenterSummaryFeatures(centers), nil
+		}
+
 		return model.FindCentersResult{
 			Centers: model.MapToCenterSummaries(centers),
 		}, nil
@@ -174,19 +279,213 @@ func (c *Centers) findCenters(_ http.ResponseWriter, r *http.Request) (interface
 	}
 }
 
+// findClusters aggregates the centers within the given bounds to clusters for the given zoom level
+func (c *Centers) findClusters(_ http.ResponseWriter, r *http.Request) (interface{}, error) {
+	findClustersRequestsCounter.Inc()
+	bounds, hasBounds, err := c.getBoundsParameter(r)
+	if err != nil || !hasBounds {
+		return nil, ErrInvalidParameters
+	}
+
+	zoom, hasZoom, err := api.GetIntParameter(r, "zoom")
+	if err != nil || !hasZoom || zoom < 0 || zoom > repositories.MaxClusterZoom {
+		return nil, ErrInvalidParameters
+	}
+
+	clusters, err := c.centersRepository.FindClusters(r.Context(), bounds, c.getSearchParameters(r), uint(zoom))
+	if err != nil {
+		logrus.WithError(err).Error("Error getting clusters")
+		return nil, err
+	}
+
+	return model.FindClustersResult{
+		Clusters: model.MapToCenterClusterDTOs(clusters),
+	}, nil
+}
+
+// searchCenters finds the centers matching the full text query given by parameter q
+func (c *Centers) searchCenters(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	searchCentersRequestsCounter.Inc()
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if len([]rune(query)) < minSearchQueryLength {
+		return nil, ErrInvalidParameters
+	}
+
+	page := repositories.ParsePageRequest(r)
+	if page.Page < 0 || page.Size <= 0 || page.Size > maxSearchPageSize {
+		return nil, ErrInvalidParameters
+	}
+
+	centers, err := c.centersRepository.Search(r.Context(), query, c.getSearchParameters(r), page)
+	if err != nil {
+		logrus.WithError(err).Error("Error searching centers")
+		return nil, err
+	}
+
+	if centers.Count == 0 {
+		emptyCentersCounter.Inc()
+	}
+	deliveredCentersCounter.Add(float64(len(centers.Result)))
+
+	return model.PageCenterSummaryDTO{
+		PagedResult: api.PagedResult{Count: centers.Count},
+		Result:      model.MapToCenterSummaries(centers.Result),
+	}, nil
+}
+
+// getTile encodes the centers within the requested tile as Mapbox Vector Tile
+func (c *Centers) getTile(w http.ResponseWriter, r *http.Request) {
+	findTilesRequestsCounter.Inc()
+	tile, err := c.getTileParameter(r)
+	if err != nil {
+		api.WriteError(w, r, ErrInvalidParameters)
+		return
+	}
+
+	minLongitude, minLatitude, maxLongitude, maxLatitude := tile.Bounds(tileBuffer)
+	bounds := domain.Bounds{
+		NorthEast: domain.Coordinates{Longitude: maxLongitude, Latitude: maxLatitude},
+		SouthWest: domain.Coordinates{Longitude: minLongitude, Latitude: minLatitude},
+	}
+
+	centers, err := c.centersRepository.FindByBounds(r.Context(), bounds, c.getSearchParameters(r), maxTileFeatures)
+	if err != nil {
+		logrus.WithError(err).Error("Error getting centers")
+		api.WriteError(w, r, err)
+		return
+	}
+	deliveredCentersCounter.Add(float64(len(centers)))
+
+	layer := mvt.NewLayer("centers", mvt.DefaultExtent)
+	for _, center := range centers {
+		x, y := tile.Project(center.Longitude, center.Latitude, layer.Extent)
+		layer.AddPoint(x, y, c.getTileProperties(&center))
+	}
+
+	api.WriteContent(w, r, mvt.ContentType, (&mvt.Tile{Layers: []*mvt.Layer{layer}}).Marshal())
+}
+
+// getTileProperties returns the properties of a center included in the vector tiles
+func (*Centers) getTileProperties(center *domain.Center) map[string]interface{} {
+	summary := model.CenterSummaryDTO{}.MapFromDomain(center)
+	properties := map[string]interface{}{
+		"uuid":       summary.UUID,
+		"name":       summary.Name,
+		"address":    summary.Address,
+		"testKinds":  strings.Join(summary.TestKinds, ","),
+		"responsive": *summary.Responsive,
+		"age":        *summary.Age,
+	}
+	if summary.Appointment != nil {
+		properties["appointment"] = *summary.Appointment
+	}
+	if summary.DCC != nil {
+		properties["dcc"] = *summary.DCC
+	}
+	if summary.Logo != nil {
+		properties["logo"] = *summary.Logo
+	}
+	if summary.Marker != nil {
+		properties["marker"] = *summary.Marker
+	}
+	return properties
+}
+
+// findNearbyCenters finds the centers closest to the given location.
+// The radius (in km) and the maximum count of centers can be restricted by the parameters radius and limit.
+func (c *Centers) findNearbyCenters(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	findCentersRequestsCounter.Inc()
+	location, hasLocation, err := c.getLocationParameter(r)
+	if err != nil {
+		return nil, ErrInvalidParameters
+	} else if !hasLocation {
+		return nil, ErrInvalidParameters
+	}
+
+	radius, hasRadius, err := api.GetFloatParameter(r, "radius")
+	if err != nil {
+		return nil, ErrInvalidParameters
+	} else if !hasRadius {
+		radius = defaultNearbyRadius
+	}
+	if radius <= 0 || radius > maxNearbyRadius {
+		return nil, ErrInvalidParameters
+	}
+
+	limit, hasLimit, err := api.GetIntParameter(r, "limit")
+	if err != nil {
+		return nil, ErrInvalidParameters
+	} else if !hasLimit {
+		limit = defaultNearbyLimit
+	}
+	if limit <= 0 || limit > maxNearbyLimit {
+		return nil, ErrInvalidParameters
+	}
+
+	searchParameters := c.getSearchParameters(r)
+	centers, err := c.centersRepository.FindNearby(r.Context(), location, radius, searchParameters, uint(limit))
+	if err != nil {
+		logrus.WithError(err).Error("Error getting nearby centers")
+		return nil, err
+	}
+
+	centersCount := len(centers)
+	if centersCount == 0 {
+		emptyCentersCounter.Inc()
+	}
+	deliveredCentersCounter.Add(float64(centersCount))
+
+	return model.FindCentersResult{
+		Centers: model.MapToCenterSummariesWithDistance(centers),
+	}, nil
+}
+
+// prepareCSVImport parses an import file (csv, xlsx or ods) without saving the centers,
+// the response is described by model.ImportFileResultDTO
 func (c *Centers) prepareCSVImport(_ http.ResponseWriter, r *http.Request) (interface{}, error) {
-	parser := &services.CsvParser{}
-	result, err := parser.Parse(r.Body)
+	profile, err := c.importProfiles.GetProfile(r.Context(), r.URL.Query().Get("profile"))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, api.HandlerError{Status: http.StatusBadRequest, Err: "unknown import profile"}
+	} else if err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxImportSize+1))
+	if err != nil {
+		return nil, err
+	} else if len(data) > maxImportSize {
+		return nil, api.HandlerError{Status: http.StatusRequestEntityTooLarge, Err: "file too large"}
+	}
+
+	// xlsx and ods files are zip archives, everything else is parsed as csv
+	parser := services.NewCsvParser(profile)
+	var result []services.ImportCenterResult
+	var summary services.ImportSummary
+	if services.IsSpreadsheet(data) {
+		result, summary, err = parser.ParseSpreadsheet(data, r.URL.Query().Get("sheet"))
+	} else {
+		result, summary, err = parser.Parse(bytes.NewReader(data))
+	}
+
 	if parseError, isParseError := err.(*csv.ParseError); isParseError {
 		return nil, api.HandlerError{
 			Status: http.StatusBadRequest,
 			Err:    parseError.Unwrap().Error(),
 		}
+	} else if errors.Is(err, services.ErrInvalidSpreadsheet) || errors.Is(err, services.ErrUnknownSheet) {
+		return nil, api.HandlerError{Status: http.StatusBadRequest, Err: err.Error()}
 	} else if err != nil {
 		return nil, err
 	}
 
-	return model.MapToImportCenterResultDTOs(result), nil
+	for i := range result {
+		if !result[i].Skipped {
+			result[i].Warnings = append(result[i].Warnings, c.plausibility.Check(r.Context(), &result[i].Center)...)
+		}
+	}
+	summary.CountResults(result)
+
+	return model.ImportFileResultDTO{}.MapFromModel(result, summary), nil
 }
 
 func (c *Centers) getAllCenters(_ http.ResponseWriter, r *http.Request) (interface{}, error) {
@@ -210,23 +509,126 @@ func (c *Centers) getAllCenters(_ http.ResponseWriter, r *http.Request) (interfa
 	}, nil
 }
 
+// getGeocodingIssues returns the centers of the current operator, whose geocoding needs attention
+func (c *Centers) getGeocodingIssues(_ http.ResponseWriter, r *http.Request) (interface{}, error) {
+	operator, err := c.operatorsService.GetCurrentOperator(r.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	centers, err := c.centersRepository.FindGeocodingIssues(r.Context(), operator.UUID, repositories.ParsePageRequest(r))
+	if err != nil {
+		return nil, err
+	}
+	return model.PageCenterDTO{
+		PagedResult: api.PagedResult{Count: centers.Count},
+		Result:      model.MapToCenterDTOs(centers.Result),
+	}, nil
+}
+
 func (c *Centers) geocodeAllCenters(_ http.ResponseWriter, r *http.Request) (interface{}, error) {
 	centers, err := c.centersRepository.FindAll()
 	if err != nil {
 		return nil, err
 	}
-	go c.centersService.PerformGeocoding(context.Background(), centers)
-	return nil, nil
+	return nil, c.centersService.EnqueueGeocoding(r.Context(), centers)
+}
+
+// updateOpeningSchedules parses the opening hours of all centers again, e.g. after the parser has been extended
+func (c *Centers) updateOpeningSchedules(_ http.ResponseWriter, r *http.Request) (interface{}, error) {
+	invalidCount, err := c.centersService.UpdateOpeningSchedules(r.Context())
+	if err != nil {
+		return nil, err
+	}
+	return model.UpdateOpeningSchedulesResult{InvalidCount: invalidCount}, nil
 }
 
-// exportCentersAsCSV exports all centers as csv file
-func (c *Centers) exportCentersAsCSV(w http.ResponseWriter, r *http.Request) {
+// purgeGeocodingCache deletes cached geocoding results, optionally only for the given address
+// or only the results older than the given count of days
+func (c *Centers) purgeGeocodingCache(_ http.ResponseWriter, r *http.Request) (interface{}, error) {
+	var before *time.Time
+	if olderThan, hasOlderThan, err := api.GetIntParameter(r, "olderThan"); err != nil || olderThan < 0 {
+		return nil, ErrInvalidParameters
+	} else if hasOlderThan {
+		tmp := time.Now().AddDate(0, 0, -olderThan)
+		before = &tmp
+	}
+
+	count, err := c.geocodingCache.Purge(r.Context(), r.URL.Query().Get("address"), before)
+	if err != nil {
+		return nil, err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"count": count,
+	}).Info("Purged geocoding cache")
+	return model.PurgeGeocodingCacheResult{Count: count}, nil
+}
+
+// getQualityReport returns all centers with implausible coordinates,
+// optionally only the centers of the operator given by the parameter operator
+func (c *Centers) getQualityReport(_ http.ResponseWriter, r *http.Request) (interface{}, error) {
+	centers, err := c.findQualityReportCenters(r)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]model.CenterQualityDTO, 0)
+	for i := range centers {
+		if warnings := c.plausibility.Check(r.Context(), &centers[i]); len(warnings) > 0 {
+			result = append(result, model.CenterQualityDTO{}.MapFromDomain(&centers[i], warnings))
+		}
+	}
+	return result, nil
+}
+
+// findQualityReportCenters finds the centers checked by the quality report together with their operator
+func (c *Centers) findQualityReportCenters(r *http.Request) ([]domain.Center, error) {
+	operatorUUID := r.URL.Query().Get("operator")
+	if operatorUUID == "" {
+		return c.centersRepository.FindAll()
+	}
+
+	operator, err := c.operators.FindById(r.Context(), operatorUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	centers, err := c.centersRepository.FindAllByOperator(r.Context(), operator.UUID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range centers {
+		centers[i].Operator = &operator
+	}
+	return centers, nil
+}
+
+// exportCenters exports all centers as csv file or as GeoJSON, if requested
+func (c *Centers) exportCenters(w http.ResponseWriter, r *http.Request) {
 	centers, err := c.centersRepository.FindAll()
 	if err != nil {
 		api.WriteError(w, r, err)
 		return
 	}
 
+	if c.isGeoJSONRequested(r) {
+		c.exportCentersAsGeoJSON(w, centers)
+	} else {
+		c.exportCentersAsCSV(w, centers)
+	}
+}
+
+// exportCentersAsGeoJSON writes the given centers as GeoJSON feature collection
+func (c *Centers) exportCentersAsGeoJSON(w http.ResponseWriter, centers []domain.Center) {
+	w.Header().Set("Content-Type", model.GeoJSONContentType)
+	if err := json.NewEncoder(w).Encode(model.MapToCenterExportFeatures(centers)); err != nil {
+		logrus.WithError(err).Error("Error writing response")
+	}
+}
+
+// exportCentersAsCSV writes the given centers as csv file
+func (c *Centers) exportCentersAsCSV(w http.ResponseWriter, centers []domain.Center) {
 	w.Header().Set("Content-Type", "text/csv")
 	if _, err := w.Write([]byte{0xEF, 0xBB, 0xBF}); err != nil {
 		logrus.WithError(err).Error("Error writing BOM")
@@ -280,6 +682,15 @@ func (c *Centers) exportCentersAsCSV(w http.ResponseWriter, r *http.Request) {
 }
 
 func (c *Centers) importCenters(_ http.ResponseWriter, r *http.Request) (interface{}, error) {
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dryRun"))
+	if partial, _ := strconv.ParseBool(r.URL.Query().Get("partial")); partial {
+		if dryRun {
+			// a dry run must never save anything, so the combination is rejected instead of ignoring one of them
+			return nil, api.HandlerError{Status: http.StatusBadRequest, Err: "dryRun is not supported for partial imports"}
+		}
+		return c.importCentersPartial(r)
+	}
+
 	var importData model.ImportCenterRequest
 	if err := api.ParseRequestBody(r, c.validate, &importData); err != nil {
 		return nil, err
@@ -290,6 +701,14 @@ func (c *Centers) importCenters(_ http.ResponseWriter, r *http.Request) (interfa
 		centers[i] = *center.MapToDomain()
 	}
 
+	if dryRun {
+		diff, err := c.centersService.DiffImport(r.Context(), centers, importData.DeleteAll)
+		if err != nil {
+			return nil, err
+		}
+		return model.ImportDiffDTO{}.MapFromModel(&diff), nil
+	}
+
 	result, err := c.centersService.ImportCenters(r.Context(), centers, importData.DeleteAll)
 	if err != nil {
 		return nil, err
@@ -297,6 +716,166 @@ func (c *Centers) importCenters(_ http.ResponseWriter, r *http.Request) (interfa
 	return model.MapToCenterDTOs(result), nil
 }
 
+// importCentersPartial imports all valid centers and responds with the status 207 and the result of each center.
+// Invalid centers don't fail the whole request, so they are validated one by one.
+func (c *Centers) importCentersPartial(r *http.Request) (interface{}, error) {
+	var importData model.ImportCenterRequest
+	if err := json.NewDecoder(r.Body).Decode(&importData); err != nil {
+		return nil, api.HandlerError{Status: http.StatusBadRequest, Err: err.Error()}
+	}
+
+	results := make([]model.ImportItemResultDTO, len(importData.Centers))
+	centers := make([]domain.Center, 0, len(importData.Centers))
+	indices := make([]int, 0, len(importData.Centers))
+	for i, center := range importData.Centers {
+		results[i].Index = i
+		if err := c.validate.Struct(center); err != nil {
+			results[i].Status, results[i].Error = getImportItemError(r, err)
+			continue
+		}
+		centers = append(centers, *center.MapToDomain())
+		indices = append(indices, i)
+	}
+
+	imported, err := c.centersService.ImportCentersPartial(r.Context(), centers, importData.DeleteAll)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, result := range imported {
+		if result.Error != nil {
+			results[indices[i]].Status, results[indices[i]].Error = getImportItemError(r, result.Error)
+		} else {
+			results[indices[i]].Status = http.StatusOK
+			results[indices[i]].Center = model.CenterDTO{}.MapFromDomain(&result.Center)
+		}
+	}
+	return api.StatusResponse{Status: http.StatusMultiStatus, Body: results}, nil
+}
+
+// submitImportJob creates a job importing the centers in the background and responds with the status 202.
+// Like importCentersPartial the centers are only validated one by one, if the partial parameter is set.
+// Centers failing this validation are not imported, their errors are reported by the job.
+func (c *Centers) submitImportJob(_ http.ResponseWriter, r *http.Request) (interface{}, error) {
+	partial, _ := strconv.ParseBool(r.URL.Query().Get("partial"))
+
+	var importData model.ImportCenterRequest
+	if partial {
+		if err := json.NewDecoder(r.Body).Decode(&importData); err != nil {
+			return nil, api.HandlerError{Status: http.StatusBadRequest, Err: err.Error()}
+		}
+	} else if err := api.ParseRequestBody(r, c.validate, &importData); err != nil {
+		return nil, err
+	}
+
+	centers := make([]domain.Center, 0, len(importData.Centers))
+	rejected := domain.ImportJobErrors{}
+	for i, center := range importData.Centers {
+		if partial {
+			if err := c.validate.Struct(center); err != nil {
+				rejected = append(rejected, domain.ImportJobError{Index: i, Error: services.ImportErrorMessage(err)})
+				continue
+			}
+		}
+		centers = append(centers, *center.MapToDomain())
+	}
+
+	job, err := c.importJobs.Submit(r.Context(), centers, rejected, importData.DeleteAll, partial)
+	if err != nil {
+		return nil, err
+	}
+	return api.StatusResponse{Status: http.StatusAccepted, Body: model.ImportJobDTO{}.MapFromDomain(&job)}, nil
+}
+
+// getImportJob returns the status of an import job of the current operator
+func (c *Centers) getImportJob(_ http.ResponseWriter, r *http.Request) (interface{}, error) {
+	job, err := c.importJobs.FindByUUID(r.Context(), chi.URLParam(r, "uuid"))
+	if err != nil {
+		return nil, err
+	}
+	return model.ImportJobDTO{}.MapFromDomain(&job), nil
+}
+
+// getImportJobs returns the import jobs of the current operator, the latest jobs first
+func (c *Centers) getImportJobs(_ http.ResponseWriter, r *http.Request) (interface{}, error) {
+	jobs, err := c.importJobs.FindAll(r.Context(), repositories.ParsePageRequest(r))
+	if err != nil {
+		return nil, err
+	}
+	return model.PageImportJobDTO{
+		PagedResult: api.PagedResult{Count: jobs.Count},
+		Result:      model.MapToImportJobDTOs(jobs.Result),
+	}, nil
+}
+
+// getImportHistory returns the import history of the current operator, or of the operator given by the path
+// for administrators, the latest imports first
+func (c *Centers) getImportHistory(_ http.ResponseWriter, r *http.Request) (interface{}, error) {
+	operatorUUID, err := c.getImportHistoryOperator(r)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := c.importRecords.FindByOperator(r.Context(), operatorUUID, repositories.ParsePageRequest(r))
+	if err != nil {
+		return nil, err
+	}
+	return model.PageImportRecordDTO{
+		PagedResult: api.PagedResult{Count: records.Count},
+		Result:      model.MapToImportRecordDTOs(records.Result),
+	}, nil
+}
+
+// rollbackLastImport rolls back the last import of the current operator, or of the operator given by the path
+// for administrators. It responds with the status 409, if the last import has already been rolled back
+// or if centers of the import have been modified since the import.
+func (c *Centers) rollbackLastImport(_ http.ResponseWriter, r *http.Request) (interface{}, error) {
+	operatorUUID, err := c.getImportHistoryOperator(r)
+	if err != nil {
+		return nil, err
+	}
+
+	record, err := c.centersService.RollbackLastImport(r.Context(), operatorUUID)
+	if errors.Is(err, services.ErrNothingToRollback) || errors.Is(err, services.ErrModifiedSinceImport) {
+		return nil, api.HandlerError{Status: http.StatusConflict, Err: err.Error()}
+	} else if err != nil {
+		return nil, err
+	}
+	return model.ImportRecordDTO{}.MapFromDomain(&record), nil
+}
+
+// getImportHistoryOperator returns the operator given by the path of the admin endpoints or the current operator
+func (c *Centers) getImportHistoryOperator(r *http.Request) (string, error) {
+	if operatorUUID := chi.URLParam(r, "operator"); operatorUUID != "" {
+		return operatorUUID, nil
+	}
+
+	operator, err := c.operatorsService.GetCurrentOperator(r.Context())
+	if err != nil {
+		return "", err
+	}
+	return operator.UUID, nil
+}
+
+// getImportItemError returns the status and the message of an error of a single center, like api.WriteError
+func getImportItemError(r *http.Request, err error) (int, *string) {
+	status := http.StatusInternalServerError
+	var validationErrors validator.ValidationErrors
+	var applicationError core.ApplicationError
+	if errors.As(err, &validationErrors) || errors.As(err, &applicationError) {
+		status = http.StatusBadRequest
+	} else if errors.Is(err, gorm.ErrRecordNotFound) {
+		status = http.StatusNotFound
+	} else {
+		logrus.WithFields(logrus.Fields{
+			"path": r.URL.Path,
+		}).WithError(err).Error("Error importing center")
+	}
+
+	message := services.ImportErrorMessage(err)
+	return status, &message
+}
+
 func (c *Centers) updateCenter(_ http.ResponseWriter, r *http.Request) (interface{}, error) {
 	centerUUID := chi.URLParam(r, "uuid")
 	logrus.WithField("uuid", centerUUID).Trace("updateCenter")
@@ -425,12 +1004,20 @@ func (c *Centers) deleteCenterByUUID(_ http.ResponseWriter, r *http.Request) (in
 	return nil, c.centersRepository.Delete(r.Context(), center)
 }
 
+// isGeoJSONRequested reports if the client requested the response as GeoJSON,
+// either by the parameter format=geojson or by accepting application/geo+json
+func (*Centers) isGeoJSONRequested(r *http.Request) bool {
+	if format, hasFormat := r.URL.Query()["format"]; hasFormat {
+		return strings.ToLower(format[0]) == "geojson"
+	}
+	return api.AcceptsMediaType(r, model.GeoJSONContentType)
+}
+
 func (*Centers) getSearchParameters(r *http.Request) repositories.SearchParameters {
 	result := repositories.SearchParameters{}
-	appointmentParameter, hasAppointment := r.URL.Query()["appointment"]
-	if hasAppointment {
-		if tmp, ok := domain.ParseAppointmentType(appointmentParameter[0]); ok {
-			result.Appointment = &tmp
+	for _, value := range api.GetStringsParameter(r, "appointment") {
+		if tmp, ok := domain.ParseAppointmentType(value); ok {
+			result.Appointments = append(result.Appointments, tmp)
 		}
 	}
 
@@ -441,10 +1028,31 @@ func (*Centers) getSearchParameters(r *http.Request) repositories.SearchParamete
 		}
 	}
 
-	testKindParameter, hasTestKind := r.URL.Query()["kind"]
-	if hasTestKind {
-		if tmp, ok := domain.ParseTestKind(testKindParameter[0]); ok {
-			result.TestKind = &tmp
+	for _, value := range api.GetStringsParameter(r, "kind") {
+		if tmp, ok := domain.ParseTestKind(value); ok {
+			result.TestKinds = append(result.TestKinds, tmp)
+		}
+	}
+
+	result.TestKindMatch = repositories.TestKindMatchAll
+	if kindMatchParameter, hasKindMatch := r.URL.Query()["kindMatch"]; hasKindMatch {
+		if strings.ToLower(kindMatchParameter[0]) == string(repositories.TestKindMatchAny) {
+			result.TestKindMatch = repositories.TestKindMatchAny
+		}
+	}
+
+	result.Operators = api.GetStringsParameter(r, "operator")
+	for _, region := range api.GetStringsParameter(r, "region") {
+		result.Regions = append(result.Regions, geocoding.GetRegionAliases(region)...)
+	}
+
+	openParameter, hasOpen := r.URL.Query()["open"]
+	if hasOpen {
+		if strings.ToLower(openParameter[0]) == "now" {
+			tmp := time.Now()
+			result.OpenAt = &tmp
+		} else if tmp, err := time.Parse(time.RFC3339, openParameter[0]); err == nil {
+			result.OpenAt = &tmp
 		}
 	}
 
@@ -488,3 +1096,37 @@ func (*Centers) getBoundsParameter(r *http.Request) (domain.Bounds, bool, error)
 
 	return bounds, true, nil
 }
+
+func (*Centers) getTileParameter(r *http.Request) (mvt.TileID, error) {
+	coordinates := make([]uint32, 3)
+	for i, name := range []string{"z", "x", "y"} {
+		value, err := strconv.ParseUint(chi.URLParam(r, name), 10, 32)
+		if err != nil {
+			return mvt.TileID{}, err
+		}
+		coordinates[i] = uint32(value)
+	}
+	return mvt.NewTileID(coordinates[0], coordinates[1], coordinates[2])
+}
+
+func (*Centers) getLocationParameter(r *http.Request) (domain.Coordinates, bool, error) {
+	var location domain.Coordinates
+	var ok bool
+	var err error
+
+	location.Latitude, ok, err = api.GetFloatParameter(r, "lat")
+	if !ok || err != nil {
+		return location, false, err
+	}
+
+	location.Longitude, ok, err = api.GetFloatParameter(r, "lng")
+	if !ok || err != nil {
+		return location, false, err
+	}
+
+	if location.Latitude < -90 || location.Latitude > 90 || location.Longitude < -180 || location.Longitude > 180 {
+		return location, true, ErrInvalidParameters
+	}
+
+	return location, true, nil
+}
@@ -0,0 +1,167 @@
+/*
+ *   Corona-Warn-App / cwa-map-backend
+ *
+ *   (C) 2020, T-Systems International GmbH
+ *
+ *   Deutsche Telekom AG and all other contributors /
+ *   copyright owners license this file to you under the Apache
+ *   License, Version 2.0 (the "License"); you may not use this
+ *   file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing,
+ *   software distributed under the License is distributed on an
+ *   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *   KIND, either express or implied.  See the License for the
+ *   specific language governing permissions and limitations
+ *   under the License.
+ */
+
+package api
+
+import (
+	"com.t-systems-mms.cwa/api/model"
+	"com.t-systems-mms.cwa/core/api"
+	"com.t-systems-mms.cwa/domain"
+	"com.t-systems-mms.cwa/repositories"
+	"com.t-systems-mms.cwa/services"
+	"context"
+	"errors"
+	"github.com/go-playground/validator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// importRecordingCenters records the calls of the import methods, all other methods are not implemented
+type importRecordingCenters struct {
+	services.Centers
+	imports int
+	diffs   int
+}
+
+func (c *importRecordingCenters) ImportCenters(_ context.Context, centers []domain.Center, _ bool) ([]domain.Center, error) {
+	c.imports++
+	return centers, nil
+}
+
+func (c *importRecordingCenters) ImportCentersPartial(_ context.Context, centers []domain.Center, _ bool) ([]services.ImportItemResult, error) {
+	c.imports++
+	return make([]services.ImportItemResult, len(centers)), nil
+}
+
+func (c *importRecordingCenters) DiffImport(_ context.Context, _ []domain.Center, _ bool) (services.ImportDiff, error) {
+	c.diffs++
+	return services.ImportDiff{}, nil
+}
+
+// boundsCenters returns no centers for any bounds, all other methods are not implemented
+type boundsCenters struct {
+	repositories.Centers
+}
+
+func (boundsCenters) FindByBounds(_ context.Context, _ domain.Bounds, _ repositories.SearchParameters, _ uint) ([]domain.Center, error) {
+	return []domain.Center{}, nil
+}
+
+func TestFindCentersVaryAccept(t *testing.T) {
+	centers := &Centers{centersRepository: boundsCenters{}}
+	for _, accept := range []string{"application/json", "application/geo+json"} {
+		t.Run(accept, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/?latne=53&lngne=14&latsw=52&lngsw=13", nil)
+			request.Header.Set("Accept", accept)
+			recorder := httptest.NewRecorder()
+			_, err := centers.findCenters(recorder, request)
+
+			assert.NoError(t, err)
+			assert.Equal(t, "Accept", recorder.Header().Get("Vary"))
+		})
+	}
+}
+
+func TestImportCentersPartialDryRun(t *testing.T) {
+	centersService := &importRecordingCenters{}
+	centers := &Centers{centersService: centersService, validate: validator.New()}
+
+	body := `{"deleteAll": true, "centers": [{"name": "Test", "address": "Teststr. 1, 10115 Berlin"}]}`
+	request := httptest.NewRequest(http.MethodPost, "/?partial=true&dryRun=true", strings.NewReader(body))
+	_, err := centers.importCenters(httptest.NewRecorder(), request)
+
+	var handlerError api.HandlerError
+	if assert.True(t, errors.As(err, &handlerError)) {
+		assert.Equal(t, http.StatusBadRequest, handlerError.Status)
+	}
+	assert.Equal(t, 0, centersService.imports, "dry run must not import centers")
+}
+
+func TestImportCentersDryRun(t *testing.T) {
+	centersService := &importRecordingCenters{}
+	centers := &Centers{centersService: centersService, validate: validator.New()}
+
+	body := `{"deleteAll": true, "centers": [{"name": "Test", "address": "Teststr. 1, 10115 Berlin"}]}`
+	request := httptest.NewRequest(http.MethodPost, "/?dryRun=true", strings.NewReader(body))
+	_, err := centers.importCenters(httptest.NewRecorder(), request)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, centersService.imports, "dry run must not import centers")
+	assert.Equal(t, 1, centersService.diffs)
+}
+
+// qualityCenters returns the centers of the operator "operator", all other methods are not implemented
+type qualityCenters struct {
+	repositories.Centers
+}
+
+func (qualityCenters) FindAll() ([]domain.Center, error) {
+	return []domain.Center{{UUID: "other", OperatorUUID: "other"}}, nil
+}
+
+func (qualityCenters) FindAllByOperator(_ context.Context, operator string) ([]domain.Center, error) {
+	if operator != "operator" {
+		return []domain.Center{}, nil
+	}
+	return []domain.Center{{UUID: "center", OperatorUUID: operator}}, nil
+}
+
+// qualityOperators knows only the operator "operator", all other methods are not implemented
+type qualityOperators struct {
+	repositories.Operators
+}
+
+func (qualityOperators) FindById(_ context.Context, id string) (domain.Operator, error) {
+	if id != "operator" {
+		return domain.Operator{}, gorm.ErrRecordNotFound
+	}
+	return domain.Operator{UUID: id, Name: "Operator"}, nil
+}
+
+// implausibleCenters reports every center as implausible
+type implausibleCenters struct{}
+
+func (implausibleCenters) Check(_ context.Context, _ *domain.Center) []string {
+	return []string{"implausible"}
+}
+
+func TestGetQualityReport(t *testing.T) {
+	centers := &Centers{centersRepository: qualityCenters{}, operators: qualityOperators{}, plausibility: implausibleCenters{}}
+
+	result, err := centers.getQualityReport(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	assert.Equal(t, "other", result.([]model.CenterQualityDTO)[0].UUID)
+
+	result, err = centers.getQualityReport(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/?operator=operator", nil))
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	assert.Equal(t, "center", result.([]model.CenterQualityDTO)[0].UUID)
+	assert.Equal(t, "Operator", result.([]model.CenterQualityDTO)[0].PartnerName)
+
+	_, err = centers.getQualityReport(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/?operator=unknown", nil))
+	assert.True(t, errors.Is(err, gorm.ErrRecordNotFound))
+}
@@ -0,0 +1,125 @@
+/*
+ *   Corona-Warn-App / cwa-map-backend
+ *
+ *   (C) 2020, T-Systems International GmbH
+ *
+ *   Deutsche Telekom AG and all other contributors /
+ *   copyright owners license this file to you under the Apache
+ *   License, Version 2.0 (the "License"); you may not use this
+ *   file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing,
+ *   software distributed under the License is distributed on an
+ *   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *   KIND, either express or implied.  See the License for the
+ *   specific language governing permissions and limitations
+ *   under the License.
+ */
+
+package api
+
+import (
+	"com.t-systems-mms.cwa/api/model"
+	"com.t-systems-mms.cwa/core/api"
+	"com.t-systems-mms.cwa/core/security"
+	"com.t-systems-mms.cwa/core/util"
+	"com.t-systems-mms.cwa/domain"
+	"com.t-systems-mms.cwa/repositories"
+	"com.t-systems-mms.cwa/services"
+	"errors"
+	"github.com/go-chi/chi"
+	"github.com/go-chi/jwtauth"
+	"github.com/go-playground/validator"
+	"net/http"
+)
+
+// ImportProfiles provides the management of the import profiles, which describe the format of center import files
+type ImportProfiles struct {
+	chi.Router
+	profilesRepository repositories.ImportProfiles
+	profilesService    services.ImportProfiles
+	validate           *validator.Validate
+}
+
+func NewImportProfilesAPI(profilesRepository repositories.ImportProfiles, profilesService services.ImportProfiles, auth *jwtauth.JWTAuth) *ImportProfiles {
+	validate := validator.New()
+	validate.RegisterTagNameFunc(util.JsonTagNameFunc)
+
+	profiles := &ImportProfiles{
+		Router:             chi.NewRouter(),
+		profilesRepository: profilesRepository,
+		profilesService:    profilesService,
+		validate:           validate,
+	}
+
+	profiles.Group(func(r chi.Router) {
+		r.Use(jwtauth.Verifier(auth))
+		r.Use(jwtauth.Authenticator)
+		r.Get("/", api.Handle(profiles.getProfiles))
+		r.Get("/default", api.Handle(profiles.getDefaultProfile))
+		r.Get("/{uuid}", api.Handle(profiles.getProfile))
+
+		r.Group(func(r chi.Router) {
+			r.Use(api.RequireRole(security.RoleAdmin))
+			r.Post("/", api.Handle(profiles.createProfile))
+			r.Put("/{uuid}", api.Handle(profiles.updateProfile))
+			r.Delete("/{uuid}", api.Handle(profiles.deleteProfile))
+		})
+	})
+
+	return profiles
+}
+
+func (c *ImportProfiles) getProfiles(_ http.ResponseWriter, r *http.Request) (interface{}, error) {
+	profiles, err := c.profilesRepository.FindAll(r.Context())
+	if err != nil {
+		return nil, err
+	}
+	return model.MapToImportProfileDTOs(profiles), nil
+}
+
+func (c *ImportProfiles) getDefaultProfile(_ http.ResponseWriter, _ *http.Request) (interface{}, error) {
+	return model.ImportProfileDTO{}.MapFromDomain(&services.DefaultImportProfile), nil
+}
+
+func (c *ImportProfiles) getProfile(_ http.ResponseWriter, r *http.Request) (interface{}, error) {
+	profile, err := c.profilesRepository.FindByUUID(r.Context(), chi.URLParam(r, "uuid"))
+	if err != nil {
+		return nil, err
+	}
+	return model.ImportProfileDTO{}.MapFromDomain(&profile), nil
+}
+
+func (c *ImportProfiles) createProfile(_ http.ResponseWriter, r *http.Request) (interface{}, error) {
+	return c.saveProfile(r, &domain.ImportProfile{})
+}
+
+func (c *ImportProfiles) updateProfile(_ http.ResponseWriter, r *http.Request) (interface{}, error) {
+	profile, err := c.profilesRepository.FindByUUID(r.Context(), chi.URLParam(r, "uuid"))
+	if err != nil {
+		return nil, err
+	}
+	return c.saveProfile(r, &profile)
+}
+
+func (c *ImportProfiles) saveProfile(r *http.Request, profile *domain.ImportProfile) (interface{}, error) {
+	var request model.ImportProfileDTO
+	if err := api.ParseRequestBody(r, c.validate, &request); err != nil {
+		return nil, err
+	}
+
+	if err := c.profilesService.Save(r.Context(), request.CopyToDomain(profile)); err != nil {
+		if errors.Is(err, services.ErrInvalidImportProfile) {
+			return nil, api.HandlerError{Status: http.StatusBadRequest, Err: err.Error()}
+		}
+		return nil, err
+	}
+	return model.ImportProfileDTO{}.MapFromDomain(profile), nil
+}
+
+func (c *ImportProfiles) deleteProfile(_ http.ResponseWriter, r *http.Request) (interface{}, error) {
+	return nil, c.profilesRepository.Delete(r.Context(), chi.URLParam(r, "uuid"))
+}
@@ -25,6 +25,7 @@ import (
 	"com.t-systems-mms.cwa/core/api"
 	"com.t-systems-mms.cwa/core/util"
 	"com.t-systems-mms.cwa/domain"
+	"com.t-systems-mms.cwa/external/geocoding"
 	"com.t-systems-mms.cwa/services"
 	"time"
 )
@@ -34,6 +35,11 @@ type PageCenterDTO struct {
 	Result []CenterDTO `json:"result"`
 }
 
+type PageCenterSummaryDTO struct {
+	api.PagedResult
+	Result []CenterSummaryDTO `json:"result"`
+}
+
 type FindCentersResult struct {
 	Centers []CenterSummaryDTO `json:"centers"`
 }
@@ -43,10 +49,164 @@ type ImportCenterRequest struct {
 	DeleteAll bool            `json:"deleteAll"`
 }
 
+// ImportItemResultDTO is the result of a single center of a partial import, the index refers to the request.
+// Center is only set for saved centers, Error only for failed centers.
+type ImportItemResultDTO struct {
+	Index  int        `json:"index"`
+	Status int        `json:"status"`
+	Center *CenterDTO `json:"center,omitempty"`
+	Error  *string    `json:"error,omitempty"`
+}
+
+// ImportDiffDTO contains the changes of a dry-run import.
+// Centers are matched by their user reference or, as probable match, by their address. Probable matches are
+// listed in created, as they are not replaced by the import. If all existing centers are deleted by the import,
+// matched centers are listed in updated or unchanged (although saved with a new UUID) and the others in deleted.
+type ImportDiffDTO struct {
+	Created   []ImportDiffEntryDTO `json:"created"`
+	Updated   []ImportDiffEntryDTO `json:"updated"`
+	Unchanged []ImportDiffEntryDTO `json:"unchanged"`
+	Deleted   []CenterDTO          `json:"deleted"`
+}
+
+type ImportDiffEntryDTO struct {
+	Center        EditCenterDTO    `json:"center"`
+	ExistingUUID  *string          `json:"existingUUID"`
+	ProbableMatch bool             `json:"probableMatch"`
+	Changes       []FieldChangeDTO `json:"changes"`
+}
+
+type FieldChangeDTO struct {
+	Field    string      `json:"field"`
+	OldValue interface{} `json:"oldValue"`
+	NewValue interface{} `json:"newValue"`
+}
+
+func (ImportDiffDTO) MapFromModel(diff *services.ImportDiff) *ImportDiffDTO {
+	if diff == nil {
+		return nil
+	}
+
+	return &ImportDiffDTO{
+		Created:   mapToImportDiffEntryDTOs(diff.Created),
+		Updated:   mapToImportDiffEntryDTOs(diff.Updated),
+		Unchanged: mapToImportDiffEntryDTOs(diff.Unchanged),
+		Deleted:   MapToCenterDTOs(diff.Deleted),
+	}
+}
+
+func mapToImportDiffEntryDTOs(entries []services.ImportDiffEntry) []ImportDiffEntryDTO {
+	result := make([]ImportDiffEntryDTO, len(entries))
+	for i, entry := range entries {
+		result[i] = ImportDiffEntryDTO{
+			Center:        EditCenterDTO{}.MapFromDomain(entry.Center),
+			ProbableMatch: entry.Probable,
+			Changes:       make([]FieldChangeDTO, len(entry.Changes)),
+		}
+		if entry.Existing != nil {
+			result[i].ExistingUUID = &entry.Existing.UUID
+		}
+		for j, change := range entry.Changes {
+			result[i].Changes[j] = FieldChangeDTO{
+				Field:    change.Field,
+				OldValue: change.OldValue,
+				NewValue: change.NewValue,
+			}
+		}
+	}
+	return result
+}
+
 type ImportCenterResult struct {
 	Center   EditCenterDTO `json:"center"`
 	Errors   []string      `json:"errors"`
 	Warnings []string      `json:"warnings"`
+	Line     int           `json:"line"`
+}
+
+// ImportSkippedLineDTO is a malformed line of an import file, which could not be parsed
+type ImportSkippedLineDTO struct {
+	Line   int      `json:"line"`
+	Errors []string `json:"errors"`
+}
+
+// ImportFileResultDTO is the response of POST /api/centers/csv. It replaces the plain list of centers,
+// which has been returned before: the parsed centers are contained in centers, the malformed lines
+// are listed separately in skipped and summary contains the counts of all rows of the file.
+type ImportFileResultDTO struct {
+	Centers []ImportCenterResult   `json:"centers"`
+	Skipped []ImportSkippedLineDTO `json:"skipped"`
+	Summary ImportSummaryDTO       `json:"summary"`
+}
+
+type ImportSummaryDTO struct {
+	Rows         int `json:"rows"`
+	Empty        int `json:"empty"`
+	Skipped      int `json:"skipped"`
+	Valid        int `json:"valid"`
+	Invalid      int `json:"invalid"`
+	WithWarnings int `json:"withWarnings"`
+}
+
+func (ImportFileResultDTO) MapFromModel(results []services.ImportCenterResult, summary services.ImportSummary) ImportFileResultDTO {
+	centers := make([]services.ImportCenterResult, 0, len(results))
+	skipped := make([]ImportSkippedLineDTO, 0)
+	for _, result := range results {
+		if result.Skipped {
+			skipped = append(skipped, ImportSkippedLineDTO{Line: result.Line, Errors: result.Errors})
+		} else {
+			centers = append(centers, result)
+		}
+	}
+
+	return ImportFileResultDTO{
+		Centers: MapToImportCenterResultDTOs(centers),
+		Skipped: skipped,
+		Summary: ImportSummaryDTO{
+			Rows:         summary.Rows,
+			Empty:        summary.Empty,
+			Skipped:      summary.Skipped,
+			Valid:        summary.Valid,
+			Invalid:      summary.Invalid,
+			WithWarnings: summary.WithWarnings,
+		},
+	}
+}
+
+// CenterQualityDTO contains a center with implausible coordinates, see services.Plausibility
+type CenterQualityDTO struct {
+	UUID          string          `json:"uuid"`
+	PartnerUUID   string          `json:"partnerUUID"`
+	PartnerName   string          `json:"partnerName"`
+	UserReference *string         `json:"userReference"`
+	Name          string          `json:"name"`
+	Address       string          `json:"address"`
+	Coordinates   *CoordinatesDTO `json:"coordinates"`
+	Geocoding     *GeocodingDTO   `json:"geocoding"`
+	Warnings      []string        `json:"warnings"`
+}
+
+func (CenterQualityDTO) MapFromDomain(center *domain.Center, warnings []string) CenterQualityDTO {
+	result := CenterQualityDTO{
+		UUID:          center.UUID,
+		PartnerUUID:   center.OperatorUUID,
+		UserReference: center.UserReference,
+		Name:          center.Name,
+		Address:       center.Address,
+		Coordinates:   CoordinatesDTO{}.MapFromModel(&center.Coordinates),
+		Geocoding:     GeocodingDTO{}.MapFromDomain(center),
+		Warnings:      warnings,
+	}
+
+	if center.Operator != nil {
+		result.PartnerName = center.Operator.Name
+	}
+	return result
+}
+
+// UpdateOpeningSchedulesResult contains the count of centers whose opening hours could not be parsed
+type UpdateOpeningSchedulesResult struct {
+	InvalidCount int `json:"invalidCount"`
 }
 
 type CenterSummaryDTO struct {
@@ -65,6 +225,8 @@ type CenterSummaryDTO struct {
 	DCC          *bool           `json:"dcc"`
 	Age          *int            `json:"age"`
 	Responsive   *bool           `json:"responsive"`
+	Distance     *float64        `json:"distance,omitempty"`
+	OpenNow      *bool           `json:"openNow,omitempty"`
 }
 
 type CenterDTO struct {
@@ -76,6 +238,90 @@ type CenterDTO struct {
 	Visible       *bool   `json:"visible"`
 	LabId         *string `json:"labId"`
 	OperatorName  *string `json:"operatorName"`
+
+	Geocoding *GeocodingDTO `json:"geocoding"`
+}
+
+// GeocodingDTO contains the state and the details of the last geocoding of a center
+type GeocodingDTO struct {
+	Status           *string `json:"status"`
+	LocationType     *string `json:"locationType"`
+	FormattedAddress *string `json:"formattedAddress"`
+	Provider         *string `json:"provider"`
+}
+
+func (GeocodingDTO) MapFromDomain(center *domain.Center) *GeocodingDTO {
+	if center == nil || center.GeocodingStatus == nil {
+		return nil
+	}
+
+	return &GeocodingDTO{
+		Status:           (*string)(center.GeocodingStatus),
+		LocationType:     (*string)(center.GeocodingLocationType),
+		FormattedAddress: center.GeocodingAddress,
+		Provider:         center.GeocodingProvider,
+	}
+}
+
+// CenterExportDTO contains all attributes of a center, which are included in the admin exports
+type CenterExportDTO struct {
+	PartnerSubject *string  `json:"partnerSubject"`
+	PartnerUUID    string   `json:"partnerUUID"`
+	PartnerName    string   `json:"partnerName"`
+	PartnerNumber  *string  `json:"partnerNumber"`
+	UserReference  *string  `json:"userReference"`
+	OperatorName   *string  `json:"operatorName"`
+	LabId          *string  `json:"labId"`
+	UUID           string   `json:"uuid"`
+	Name           string   `json:"name"`
+	Email          *string  `json:"email"`
+	Address        string   `json:"address"`
+	Zip            *string  `json:"zip"`
+	Region         string   `json:"region"`
+	DCC            *bool    `json:"dcc"`
+	EnterDate      *string  `json:"enterDate"`
+	LeaveDate      *string  `json:"leaveDate"`
+	TestKinds      []string `json:"testKinds"`
+	Appointment    *string  `json:"appointment"`
+	Message        *string  `json:"message"`
+	LastUpdate     *string  `json:"lastUpdate"`
+	Visible        *bool    `json:"visible"`
+	Notified       *string  `json:"notified"`
+}
+
+func (CenterExportDTO) MapFromDomain(center *domain.Center) *CenterExportDTO {
+	if center == nil {
+		return nil
+	}
+
+	result := &CenterExportDTO{
+		PartnerUUID:   center.OperatorUUID,
+		UserReference: center.UserReference,
+		OperatorName:  center.OperatorName,
+		LabId:         center.LabId,
+		UUID:          center.UUID,
+		Name:          center.Name,
+		Email:         center.Email,
+		Address:       center.Address,
+		Zip:           center.Zip,
+		Region:        geocoding.GetRegionTranslation(center.Region),
+		DCC:           center.DCC,
+		EnterDate:     mapDateToString(center.EnterDate),
+		LeaveDate:     mapDateToString(center.LeaveDate),
+		TestKinds:     center.TestKinds,
+		Appointment:   (*string)(center.Appointment),
+		Message:       center.Message,
+		LastUpdate:    mapTimestampToString(center.LastUpdate),
+		Visible:       center.Visible,
+		Notified:      mapTimestampToString(center.Notified),
+	}
+
+	if center.Operator != nil {
+		result.PartnerSubject = center.Operator.Subject
+		result.PartnerName = center.Operator.Name
+		result.PartnerNumber = center.Operator.OperatorNumber
+	}
+	return result
 }
 
 func (CenterSummaryDTO) MapFromDomain(center *domain.Center) *CenterSummaryDTO {
@@ -94,6 +340,12 @@ func (CenterSummaryDTO) MapFromDomain(center *domain.Center) *CenterSummaryDTO {
 
 	age := int(time.Now().Sub(*center.LastUpdate).Hours() / 24 / 7)
 
+	var openNow *bool
+	if len(center.OpeningSchedule) > 0 {
+		tmp := center.OpeningSchedule.IsOpen(time.Now())
+		openNow = &tmp
+	}
+
 	return &CenterSummaryDTO{
 		UUID:         center.UUID,
 		Name:         center.Name,
@@ -110,6 +362,7 @@ func (CenterSummaryDTO) MapFromDomain(center *domain.Center) *CenterSummaryDTO {
 		DCC:          center.DCC,
 		Age:          &age,
 		Responsive:   &responsive,
+		OpenNow:      openNow,
 	}
 
 }
@@ -122,6 +375,17 @@ func MapToCenterSummaries(centers []domain.Center) []CenterSummaryDTO {
 	return result
 }
 
+// MapToCenterSummariesWithDistance maps the given centers and adds their distance in km
+func MapToCenterSummariesWithDistance(centers []domain.CenterWithDistance) []CenterSummaryDTO {
+	result := make([]CenterSummaryDTO, len(centers))
+	for i, center := range centers {
+		distance := center.Distance
+		result[i] = *CenterSummaryDTO{}.MapFromDomain(&center.Center)
+		result[i].Distance = &distance
+	}
+	return result
+}
+
 func (CenterDTO) MapFromDomain(center *domain.Center) *CenterDTO {
 	if center == nil {
 		return nil
@@ -136,6 +400,7 @@ func (CenterDTO) MapFromDomain(center *domain.Center) *CenterDTO {
 		Visible:          center.Visible,
 		LabId:            center.LabId,
 		OperatorName:     center.OperatorName,
+		Geocoding:        GeocodingDTO{}.MapFromDomain(center),
 	}
 }
 
@@ -264,6 +529,7 @@ func MapToImportCenterResultDTO(result services.ImportCenterResult) ImportCenter
 		Center:   EditCenterDTO{}.MapFromDomain(result.Center),
 		Errors:   result.Errors,
 		Warnings: result.Warnings,
+		Line:     result.Line,
 	}
 }
 
@@ -282,3 +548,11 @@ func mapDateToString(str *time.Time) *string {
 	tmp := str.Format("02.01.2006")
 	return &tmp
 }
+
+func mapTimestampToString(timestamp *time.Time) *string {
+	if timestamp == nil {
+		return nil
+	}
+	tmp := timestamp.Format(time.RFC3339)
+	return &tmp
+}
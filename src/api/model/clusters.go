@@ -0,0 +1,76 @@
+/*
+ *   Corona-Warn-App / cwa-map-backend
+ *
+ *   (C) 2020, T-Systems International GmbH
+ *
+ *   Deutsche Telekom AG and all other contributors /
+ *   copyright owners license this file to you under the Apache
+ *   License, Version 2.0 (the "License"); you may not use this
+ *   file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing,
+ *   software distributed under the License is distributed on an
+ *   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *   KIND, either express or implied.  See the License for the
+ *   specific language governing permissions and limitations
+ *   under the License.
+ */
+
+package model
+
+import (
+	"com.t-systems-mms.cwa/domain"
+	"com.t-systems-mms.cwa/repositories"
+)
+
+type FindClustersResult struct {
+	Clusters []CenterClusterDTO `json:"clusters"`
+}
+
+type CenterClusterDTO struct {
+	Coordinates *CoordinatesDTO `json:"coordinates"`
+	Bounds      *BoundsDTO      `json:"bounds"`
+	Count       int             `json:"count"`
+	TestKinds   map[string]int  `json:"testKinds"`
+}
+
+func (CenterClusterDTO) MapFromModel(cluster *repositories.CenterCluster) *CenterClusterDTO {
+	if cluster == nil {
+		return nil
+	}
+
+	return &CenterClusterDTO{
+		Coordinates: CoordinatesDTO{}.MapFromModel(&domain.Coordinates{
+			Longitude: cluster.Longitude,
+			Latitude:  cluster.Latitude,
+		}),
+		Bounds: BoundsDTO{}.MapFromModel(&domain.Bounds{
+			NorthEast: domain.Coordinates{
+				Longitude: cluster.MaxLongitude,
+				Latitude:  cluster.MaxLatitude,
+			},
+			SouthWest: domain.Coordinates{
+				Longitude: cluster.MinLongitude,
+				Latitude:  cluster.MinLatitude,
+			},
+		}),
+		Count: cluster.Count,
+		TestKinds: map[string]int{
+			string(domain.TestKindAntigen):     cluster.AntigenCount,
+			string(domain.TestKindPCR):         cluster.PcrCount,
+			string(domain.TestKindVaccination): cluster.VaccinationCount,
+			string(domain.TestKindAntibody):    cluster.AntibodyCount,
+		},
+	}
+}
+
+func MapToCenterClusterDTOs(clusters []repositories.CenterCluster) []CenterClusterDTO {
+	result := make([]CenterClusterDTO, len(clusters))
+	for i, cluster := range clusters {
+		result[i] = *CenterClusterDTO{}.MapFromModel(&cluster)
+	}
+	return result
+}
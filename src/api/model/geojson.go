@@ -0,0 +1,82 @@
+/*
+ *   Corona-Warn-App / cwa-map-backend
+ *
+ *   (C) 2020, T-Systems International GmbH
+ *
+ *   Deutsche Telekom AG and all other contributors /
+ *   copyright owners license this file to you under the Apache
+ *   License, Version 2.0 (the "License"); you may not use this
+ *   file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing,
+ *   software distributed under the License is distributed on an
+ *   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *   KIND, either express or implied.  See the License for the
+ *   specific language governing permissions and limitations
+ *   under the License.
+ */
+
+package model
+
+import "com.t-systems-mms.cwa/domain"
+
+// GeoJSONContentType is the media type of GeoJSON documents as defined by RFC 7946
+const GeoJSONContentType = "application/geo+json"
+
+type FeatureCollection struct {
+	Type     string    `json:"type"`
+	Features []Feature `json:"features"`
+}
+
+type Feature struct {
+	Type       string        `json:"type"`
+	ID         string        `json:"id,omitempty"`
+	Geometry   PointGeometry `json:"geometry"`
+	Properties interface{}   `json:"properties"`
+}
+
+type PointGeometry struct {
+	Type string `json:"type"`
+	// Coordinates contains longitude and latitude (in this order)
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+func NewFeatureCollection(features []Feature) FeatureCollection {
+	return FeatureCollection{
+		Type:     "FeatureCollection",
+		Features: features,
+	}
+}
+
+func NewPointFeature(id string, coordinates domain.Coordinates, properties interface{}) Feature {
+	return Feature{
+		Type: "Feature",
+		ID:   id,
+		Geometry: PointGeometry{
+			Type:        "Point",
+			Coordinates: [2]float64{coordinates.Longitude, coordinates.Latitude},
+		},
+		Properties: properties,
+	}
+}
+
+// MapToCenterSummaryFeatures maps the given centers to a feature collection using the center summaries as properties
+func MapToCenterSummaryFeatures(centers []domain.Center) FeatureCollection {
+	features := make([]Feature, len(centers))
+	for i, center := range centers {
+		features[i] = NewPointFeature(center.UUID, center.Coordinates, CenterSummaryDTO{}.MapFromDomain(&center))
+	}
+	return NewFeatureCollection(features)
+}
+
+// MapToCenterExportFeatures maps the given centers to a feature collection using all exported attributes as properties
+func MapToCenterExportFeatures(centers []domain.Center) FeatureCollection {
+	features := make([]Feature, len(centers))
+	for i, center := range centers {
+		features[i] = NewPointFeature(center.UUID, center.Coordinates, CenterExportDTO{}.MapFromDomain(&center))
+	}
+	return NewFeatureCollection(features)
+}
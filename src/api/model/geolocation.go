@@ -72,3 +72,34 @@ func (c CoordinatesDTO) MapFromModel(coordinates *domain.Coordinates) *Coordinat
 	c.Latitude = &lat
 	return &c
 }
+
+// ReverseGeocodeResultDTO contains the address found for coordinates
+type ReverseGeocodeResultDTO struct {
+	Address     string          `json:"address"`
+	Zip         *string         `json:"zip"`
+	Region      *string         `json:"region"`
+	Coordinates *CoordinatesDTO `json:"coordinates"`
+}
+
+func (ReverseGeocodeResultDTO) MapFromModel(result *geocoding.Result) *ReverseGeocodeResultDTO {
+	if result == nil {
+		return nil
+	}
+
+	dto := &ReverseGeocodeResultDTO{
+		Address:     result.Address,
+		Coordinates: CoordinatesDTO{}.MapFromModel(&result.Coordinates),
+	}
+	if result.Zip != "" {
+		dto.Zip = &result.Zip
+	}
+	if result.Region != "" {
+		dto.Region = &result.Region
+	}
+	return dto
+}
+
+// PurgeGeocodingCacheResult contains the count of deleted geocoding cache entries
+type PurgeGeocodingCacheResult struct {
+	Count int64 `json:"count"`
+}
@@ -0,0 +1,85 @@
+/*
+ *   Corona-Warn-App / cwa-map-backend
+ *
+ *   (C) 2020, T-Systems International GmbH
+ *
+ *   Deutsche Telekom AG and all other contributors /
+ *   copyright owners license this file to you under the Apache
+ *   License, Version 2.0 (the "License"); you may not use this
+ *   file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing,
+ *   software distributed under the License is distributed on an
+ *   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *   KIND, either express or implied.  See the License for the
+ *   specific language governing permissions and limitations
+ *   under the License.
+ */
+
+package model
+
+import (
+	"com.t-systems-mms.cwa/core/api"
+	"com.t-systems-mms.cwa/domain"
+)
+
+type PageImportJobDTO struct {
+	api.PagedResult
+	Result []ImportJobDTO `json:"result"`
+}
+
+// ImportJobDTO contains the status of an import job, the index of the errors refers to the centers of the request
+type ImportJobDTO struct {
+	UUID      string              `json:"uuid"`
+	Status    string              `json:"status"`
+	DeleteAll bool                `json:"deleteAll"`
+	Partial   bool                `json:"partial"`
+	Total     int                 `json:"total"`
+	Processed int                 `json:"processed"`
+	Succeeded int                 `json:"succeeded"`
+	Failed    int                 `json:"failed"`
+	Errors    []ImportJobErrorDTO `json:"errors"`
+	Error     *string             `json:"error"`
+	Created   *string             `json:"created"`
+	Started   *string             `json:"started"`
+	Finished  *string             `json:"finished"`
+}
+
+type ImportJobErrorDTO struct {
+	Index int    `json:"index"`
+	Error string `json:"error"`
+}
+
+func (ImportJobDTO) MapFromDomain(job *domain.ImportJob) ImportJobDTO {
+	result := ImportJobDTO{
+		UUID:      job.UUID,
+		Status:    string(job.Status),
+		DeleteAll: job.DeleteAll,
+		Partial:   job.Partial,
+		Total:     job.Total,
+		Processed: job.Processed,
+		Succeeded: job.Succeeded,
+		Failed:    job.Failed,
+		Errors:    make([]ImportJobErrorDTO, len(job.Errors)),
+		Error:     job.Error,
+		Created:   mapTimestampToString(&job.Created),
+		Started:   mapTimestampToString(job.Started),
+		Finished:  mapTimestampToString(job.Finished),
+	}
+
+	for i, err := range job.Errors {
+		result.Errors[i] = ImportJobErrorDTO{Index: err.Index, Error: err.Error}
+	}
+	return result
+}
+
+func MapToImportJobDTOs(jobs []domain.ImportJob) []ImportJobDTO {
+	result := make([]ImportJobDTO, len(jobs))
+	for i := range jobs {
+		result[i] = ImportJobDTO{}.MapFromDomain(&jobs[i])
+	}
+	return result
+}
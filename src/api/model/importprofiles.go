@@ -0,0 +1,74 @@
+/*
+ *   Corona-Warn-App / cwa-map-backend
+ *
+ *   (C) 2020, T-Systems International GmbH
+ *
+ *   Deutsche Telekom AG and all other contributors /
+ *   copyright owners license this file to you under the Apache
+ *   License, Version 2.0 (the "License"); you may not use this
+ *   file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing,
+ *   software distributed under the License is distributed on an
+ *   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *   KIND, either express or implied.  See the License for the
+ *   specific language governing permissions and limitations
+ *   under the License.
+ */
+
+package model
+
+import "com.t-systems-mms.cwa/domain"
+
+type ImportProfileDTO struct {
+	UUID        *string             `json:"uuid"`
+	Name        string              `json:"name" validate:"required,max=64"`
+	Delimiter   string              `json:"delimiter" validate:"required,len=1"`
+	HeaderRows  int                 `json:"headerRows" validate:"min=1,max=10"`
+	DateFormats []string            `json:"dateFormats" validate:"min=1,dive,required,max=32"`
+	TrueValues  []string            `json:"trueValues" validate:"dive,required,max=32"`
+	Columns     map[string][]string `json:"columns" validate:"required"`
+}
+
+func (ImportProfileDTO) MapFromDomain(profile *domain.ImportProfile) *ImportProfileDTO {
+	if profile == nil {
+		return nil
+	}
+
+	result := &ImportProfileDTO{
+		Name:        profile.Name,
+		Delimiter:   profile.Delimiter,
+		HeaderRows:  profile.HeaderRows,
+		DateFormats: profile.DateFormats,
+		TrueValues:  profile.TrueValues,
+		Columns:     profile.Columns,
+	}
+	if profile.UUID != "" {
+		result.UUID = &profile.UUID
+	}
+	return result
+}
+
+func (p ImportProfileDTO) CopyToDomain(dst *domain.ImportProfile) *domain.ImportProfile {
+	dst.Name = p.Name
+	dst.Delimiter = p.Delimiter
+	dst.HeaderRows = p.HeaderRows
+	dst.DateFormats = p.DateFormats
+	dst.TrueValues = p.TrueValues
+	if dst.TrueValues == nil {
+		dst.TrueValues = make([]string, 0)
+	}
+	dst.Columns = p.Columns
+	return dst
+}
+
+func MapToImportProfileDTOs(profiles []domain.ImportProfile) []ImportProfileDTO {
+	result := make([]ImportProfileDTO, len(profiles))
+	for i := range profiles {
+		result[i] = *ImportProfileDTO{}.MapFromDomain(&profiles[i])
+	}
+	return result
+}
@@ -0,0 +1,69 @@
+/*
+ *   Corona-Warn-App / cwa-map-backend
+ *
+ *   (C) 2020, T-Systems International GmbH
+ *
+ *   Deutsche Telekom AG and all other contributors /
+ *   copyright owners license this file to you under the Apache
+ *   License, Version 2.0 (the "License"); you may not use this
+ *   file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing,
+ *   software distributed under the License is distributed on an
+ *   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *   KIND, either express or implied.  See the License for the
+ *   specific language governing permissions and limitations
+ *   under the License.
+ */
+
+package model
+
+import (
+	"com.t-systems-mms.cwa/core/api"
+	"com.t-systems-mms.cwa/domain"
+)
+
+type PageImportRecordDTO struct {
+	api.PagedResult
+	Result []ImportRecordDTO `json:"result"`
+}
+
+// ImportRecordDTO is an entry of the import history, the counts refer to the changed centers
+type ImportRecordDTO struct {
+	UUID         string  `json:"uuid"`
+	ImportedBy   string  `json:"importedBy"`
+	Imported     *string `json:"imported"`
+	DeleteAll    bool    `json:"deleteAll"`
+	Partial      bool    `json:"partial"`
+	Created      int     `json:"created"`
+	Updated      int     `json:"updated"`
+	Deleted      int     `json:"deleted"`
+	RolledBack   *string `json:"rolledBack"`
+	RolledBackBy *string `json:"rolledBackBy"`
+}
+
+func (ImportRecordDTO) MapFromDomain(record *domain.ImportRecord) ImportRecordDTO {
+	return ImportRecordDTO{
+		UUID:         record.UUID,
+		ImportedBy:   record.ImportedBy,
+		Imported:     mapTimestampToString(&record.Imported),
+		DeleteAll:    record.DeleteAll,
+		Partial:      record.Partial,
+		Created:      record.Created,
+		Updated:      record.Updated,
+		Deleted:      record.Deleted,
+		RolledBack:   mapTimestampToString(record.RolledBack),
+		RolledBackBy: record.RolledBackBy,
+	}
+}
+
+func MapToImportRecordDTOs(records []domain.ImportRecord) []ImportRecordDTO {
+	result := make([]ImportRecordDTO, len(records))
+	for i := range records {
+		result[i] = ImportRecordDTO{}.MapFromDomain(&records[i])
+	}
+	return result
+}
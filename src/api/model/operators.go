@@ -31,6 +31,9 @@ type OperatorDTO struct {
 	Logo           *string `json:"logo"`
 	MarkerIcon     *string `json:"markerIcon"`
 	ReportReceiver *string `json:"reportReceiver" validate:"oneof=operator center"`
+
+	// ImportProfile is the uuid of the import profile used for the imports of the operator
+	ImportProfile *string `json:"importProfile"`
 }
 
 func MapToOperatorDTO(operator *domain.Operator) *OperatorDTO {
@@ -58,5 +61,6 @@ func MapToOperatorDTO(operator *domain.Operator) *OperatorDTO {
 		MarkerIcon:     markerIcon,
 		Email:          operator.Email,
 		ReportReceiver: operator.BugReportsReceiver,
+		ImportProfile:  operator.ImportProfileUUID,
 	}
 }
@@ -30,11 +30,13 @@ import (
 	"com.t-systems-mms.cwa/repositories"
 	"com.t-systems-mms.cwa/services"
 	"encoding/csv"
+	"errors"
 	"github.com/go-chi/chi"
 	"github.com/go-chi/jwtauth"
 	"github.com/go-playground/validator"
 	"github.com/sirupsen/logrus"
 	"github.com/vincent-petithory/dataurl"
+	"gorm.io/gorm"
 	"image"
 	"net/http"
 )
@@ -43,10 +45,11 @@ type Operators struct {
 	chi.Router
 	operatorsRepository repositories.Operators
 	operatorsService    services.Operators
+	importProfiles      repositories.ImportProfiles
 	validate            *validator.Validate
 }
 
-func NewOperatorsAPI(operatorsRepository repositories.Operators, operatorsService services.Operators, auth *jwtauth.JWTAuth) *Operators {
+func NewOperatorsAPI(operatorsRepository repositories.Operators, operatorsService services.Operators, importProfiles repositories.ImportProfiles, caching CachingConfig, auth *jwtauth.JWTAuth) *Operators {
 	validate := validator.New()
 	validate.RegisterTagNameFunc(util.JsonTagNameFunc)
 
@@ -54,11 +57,12 @@ func NewOperatorsAPI(operatorsRepository repositories.Operators, operatorsServic
 		Router:              chi.NewRouter(),
 		operatorsService:    operatorsService,
 		operatorsRepository: operatorsRepository,
+		importProfiles:      importProfiles,
 		validate:            validate,
 	}
 
-	operators.Get("/{operator}/logo", operators.GetOperatorLogo)
-	operators.Get("/{operator}/marker", operators.GetOperatorMarker)
+	operators.With(cacheControl(caching.AssetsMaxAge)).Get("/{operator}/logo", operators.GetOperatorLogo)
+	operators.With(cacheControl(caching.AssetsMaxAge)).Get("/{operator}/marker", operators.GetOperatorMarker)
 	operators.Get("/confirm/{token}", operators.ConfirmNotification)
 
 	operators.Group(func(r chi.Router) {
@@ -93,6 +97,20 @@ func (c *Operators) SaveCurrentOperator(w http.ResponseWriter, r *http.Request)
 	operator.Email = request.Email
 	operator.BugReportsReceiver = request.ReportReceiver
 
+	// the import profile is only changed if present, an empty value selects the default profile
+	if request.ImportProfile != nil {
+		operator.ImportProfileUUID = nil
+		if *request.ImportProfile != "" {
+			if _, err := c.importProfiles.FindByUUID(r.Context(), *request.ImportProfile); err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return nil, api.HandlerError{Status: http.StatusBadRequest, Err: "unknown import profile"}
+				}
+				return nil, err
+			}
+			operator.ImportProfileUUID = request.ImportProfile
+		}
+	}
+
 	if request.Logo != nil {
 		data, err := dataurl.DecodeString(*request.Logo)
 		if err != nil {
@@ -143,8 +161,8 @@ func (c *Operators) GetOperatorMarker(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
-		w.Header().Set("Content-Type", dataUrl.ContentType())
-		_, _ = w.Write(dataUrl.Data)
+		api.WriteContent(w, r, dataUrl.ContentType(), dataUrl.Data)
+		return
 	}
 	w.WriteHeader(http.StatusNotFound)
 }
@@ -164,8 +182,8 @@ func (c *Operators) GetOperatorLogo(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
-		w.Header().Set("Content-Type", dataUrl.ContentType())
-		_, _ = w.Write(dataUrl.Data)
+		api.WriteContent(w, r, dataUrl.ContentType(), dataUrl.Data)
+		return
 	}
 	w.WriteHeader(http.StatusNotFound)
 }
@@ -22,7 +22,9 @@
 package main
 
 import (
+	cwaapi "com.t-systems-mms.cwa/api"
 	"com.t-systems-mms.cwa/external/geocoding"
+	"com.t-systems-mms.cwa/repositories"
 	"com.t-systems-mms.cwa/services"
 	"errors"
 	"fmt"
@@ -31,6 +33,7 @@ import (
 	"os"
 	"reflect"
 	"strconv"
+	"strings"
 )
 
 type Config struct {
@@ -38,11 +41,16 @@ type Config struct {
 	Logging        LogConfig
 	Database       DatabaseConfig
 	Google         geocoding.GoogleGeocoderConfig
+	Geocoding      GeocodingConfig
 	Authentication AuthenticationConfig
 	BugReports     services.BugReportConfig
 	Email          services.EmailConfig
 	Operators      services.OperatorsServiceConfig
 	Centers        services.CentersServiceConfig
+	CentersCache   repositories.CentersCacheConfig
+	Caching        cwaapi.CachingConfig
+	Plausibility   services.PlausibilityConfig
+	ImportJobs     services.ImportJobsConfig
 }
 
 type DatabaseConfig struct {
@@ -56,6 +64,25 @@ type DatabaseConfig struct {
 	ConnMaxLifetime int
 }
 
+type GeocodingConfig struct {
+	// CacheTTL is the time (in days) geocoding results are cached
+	CacheTTL int
+
+	// NegativeCacheTTL is the time (in hours) missing or ambiguous geocoding results are cached
+	NegativeCacheTTL int
+
+	// Providers are the names of the geocoding providers (google or nominatim) in the order they are used
+	Providers []string
+	Nominatim geocoding.NominatimGeocoderConfig
+
+	// PostalCodesFile is the dataset of the offline geocoder used for searches and for the plausibility checks
+	// of the postal code distance, see geocoding.LoadPostalCodeGeocoder.
+	// The offline geocoder is disabled, if it is empty. It defaults to the bundled dataset.
+	PostalCodesFile string
+
+	Worker services.GeocodingWorkerConfig
+}
+
 type ServerConfig struct {
 	Listen string
 }
@@ -91,6 +118,7 @@ func LoadConfig() error {
 	}
 
 	appConfig.Server.Listen = getEnv("CWA_MAP_SERVER_LISTEN", ":9090")
+	appConfig.Geocoding.PostalCodesFile = getEnv("CWA_MAP_POSTAL_CODES_FILE", "geocoding/postalcodes.csv")
 	appConfig.Logging.Level = getEnv("CWA_MAP_LOG_LEVEL", "info")
 	appConfig.Logging.LogSQL, err = strconv.ParseBool(getEnv("CWA_MAP_LOG_SQL", "false"))
 	if err != nil {
@@ -173,10 +201,104 @@ func LoadConfig() error {
 		return err
 	}
 
+	// Geocoding
 	if err := readStringSecret(logicalClient, backend+"/data/google-maps", "api-key",
 		&appConfig.Google.ApiKey); err != nil {
-		return err
+		logrus.WithError(err).Warn("Missing google maps api key")
+	}
+
+	if err := readStringSecret(logicalClient, backend+"/data/google-maps", "country",
+		&appConfig.Google.Country); err != nil {
+		appConfig.Google.Country = "de"
+	}
+
+	if err := readStringSecret(logicalClient, backend+"/data/google-maps", "region",
+		&appConfig.Google.Region); err != nil {
+		appConfig.Google.Region = appConfig.Google.Country
+	}
+
+	// optional, google selects the language if not set
+	_ = readStringSecret(logicalClient, backend+"/data/google-maps", "language",
+		&appConfig.Google.Language)
+
+	var resultTypes string
+	if err := readStringSecret(logicalClient, backend+"/data/google-maps", "result-types",
+		&resultTypes); err != nil {
+		resultTypes = "street_address"
+	}
+	for _, resultType := range strings.Split(resultTypes, ",") {
+		if resultType = strings.TrimSpace(resultType); resultType != "" {
+			appConfig.Google.ResultTypes = append(appConfig.Google.ResultTypes, resultType)
+		}
+	}
+
+	var providers string
+	if err := readStringSecret(logicalClient, backend+"/data/geocoding", "providers",
+		&providers); err != nil {
+		providers = "google"
+	}
+	for _, provider := range strings.Split(providers, ",") {
+		if provider = strings.TrimSpace(provider); provider != "" {
+			appConfig.Geocoding.Providers = append(appConfig.Geocoding.Providers, provider)
+		}
+	}
+
+	if err := readStringSecret(logicalClient, backend+"/data/geocoding", "nominatim-url",
+		&appConfig.Geocoding.Nominatim.BaseURL); err != nil {
+		appConfig.Geocoding.Nominatim.BaseURL = "https://nominatim.openstreetmap.org"
+	}
+
+	if err := readStringSecret(logicalClient, backend+"/data/geocoding", "nominatim-user-agent",
+		&appConfig.Geocoding.Nominatim.UserAgent); err != nil {
+		appConfig.Geocoding.Nominatim.UserAgent = "cwa-map-backend"
+	}
+
+	// optional, the email is only sent if configured
+	_ = readStringSecret(logicalClient, backend+"/data/geocoding", "nominatim-email",
+		&appConfig.Geocoding.Nominatim.Email)
+
+	if err := readStringSecret(logicalClient, backend+"/data/geocoding", "nominatim-country-codes",
+		&appConfig.Geocoding.Nominatim.CountryCodes); err != nil {
+		appConfig.Geocoding.Nominatim.CountryCodes = appConfig.Google.Country
+	}
+
+	if err := readIntSecret(logicalClient, backend+"/data/geocoding", "cache-ttl",
+		&appConfig.Geocoding.CacheTTL); err != nil {
+		appConfig.Geocoding.CacheTTL = 90
+	}
+
+	if err := readIntSecret(logicalClient, backend+"/data/geocoding", "negative-cache-ttl",
+		&appConfig.Geocoding.NegativeCacheTTL); err != nil {
+		appConfig.Geocoding.NegativeCacheTTL = 24
+	}
+
+	if err := readIntSecret(logicalClient, backend+"/data/geocoding", "workers",
+		&appConfig.Geocoding.Worker.Workers); err != nil {
+		appConfig.Geocoding.Worker.Workers = 4
+	}
+
+	if err := readIntSecret(logicalClient, backend+"/data/geocoding", "qps",
+		&appConfig.Geocoding.Worker.QPS); err != nil {
+		appConfig.Geocoding.Worker.QPS = 10
+	}
+
+	if err := readIntSecret(logicalClient, backend+"/data/geocoding", "max-attempts",
+		&appConfig.Geocoding.Worker.MaxAttempts); err != nil {
+		appConfig.Geocoding.Worker.MaxAttempts = 5
+	}
+
+	if err := readIntSecret(logicalClient, backend+"/data/geocoding", "poll-interval",
+		&appConfig.Geocoding.Worker.PollInterval); err != nil {
+		appConfig.Geocoding.Worker.PollInterval = 10
+	}
+
+	appConfig.Plausibility.Country = appConfig.Google.Country
+	maxPostalCodeDistance := 0
+	if err := readIntSecret(logicalClient, backend+"/data/geocoding", "plausibility-max-distance",
+		&maxPostalCodeDistance); err != nil {
+		maxPostalCodeDistance = 30
 	}
+	appConfig.Plausibility.MaxPostalCodeDistance = float64(maxPostalCodeDistance)
 
 	// Operators
 	if err := readIntSecret(logicalClient, backend+"/data/operators", "max-last-update-age",
@@ -210,6 +332,42 @@ func LoadConfig() error {
 		appConfig.Centers.NotificationInterval = 24
 	}
 
+	if err := readIntSecret(logicalClient, backend+"/data/centers", "import-poll-interval",
+		&appConfig.ImportJobs.PollInterval); err != nil {
+		appConfig.ImportJobs.PollInterval = 5
+	}
+
+	if err := readIntSecret(logicalClient, backend+"/data/centers", "import-retention",
+		&appConfig.ImportJobs.Retention); err != nil {
+		appConfig.ImportJobs.Retention = 30
+	}
+
+	if err := readBoolSecret(logicalClient, backend+"/data/centers", "cache-enabled",
+		&appConfig.CentersCache.Enabled); err != nil {
+		appConfig.CentersCache.Enabled = false
+	}
+
+	if err := readIntSecret(logicalClient, backend+"/data/centers", "cache-refresh-interval",
+		&appConfig.CentersCache.RefreshInterval); err != nil {
+		appConfig.CentersCache.RefreshInterval = 5
+	}
+
+	// HTTP caching
+	if err := readIntSecret(logicalClient, backend+"/data/caching", "search-max-age",
+		&appConfig.Caching.SearchMaxAge); err != nil {
+		appConfig.Caching.SearchMaxAge = 60
+	}
+
+	if err := readIntSecret(logicalClient, backend+"/data/caching", "tiles-max-age",
+		&appConfig.Caching.TilesMaxAge); err != nil {
+		appConfig.Caching.TilesMaxAge = 300
+	}
+
+	if err := readIntSecret(logicalClient, backend+"/data/caching", "assets-max-age",
+		&appConfig.Caching.AssetsMaxAge); err != nil {
+		appConfig.Caching.AssetsMaxAge = 24 * 60 * 60
+	}
+
 	return nil
 }
 
@@ -283,3 +441,17 @@ func readIntSecret(logical *api.Logical, path, name string, target *int) error {
 		}
 	}
 }
+
+func readBoolSecret(logical *api.Logical, path, name string, target *bool) error {
+	var stringValue string
+	if err := readStringSecret(logical, path, name, &stringValue); err != nil {
+		return err
+	} else {
+		if boolValue, err := strconv.ParseBool(stringValue); err != nil {
+			return err
+		} else {
+			*target = boolValue
+			return nil
+		}
+	}
+}
@@ -27,6 +27,7 @@ import (
 	"com.t-systems-mms.cwa/repositories"
 	"com.t-systems-mms.cwa/services"
 	"crypto/rsa"
+	"errors"
 	"fmt"
 	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/middleware"
@@ -35,7 +36,6 @@ import (
 	"github.com/sirupsen/logrus"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"math/rand"
 	"net/http"
 	"os"
 	"os/signal"
@@ -46,7 +46,6 @@ import (
 
 func main() {
 	logrus.Info("Starting application")
-	rand.Seed(time.Now().Unix())
 
 	if err := LoadConfig(); err != nil {
 		logrus.WithError(err).Fatal("Error loading config")
@@ -60,12 +59,6 @@ func main() {
 		os.Exit(1)
 	}
 
-	geocoder, err := geocoding.NewGoogleGeocoder(appConfig.Google)
-	if err != nil {
-		logrus.WithError(err).Fatal("Error creating google client")
-		os.Exit(1)
-	}
-
 	db, err := gorm.Open(postgres.Open(fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d",
 		appConfig.Database.Host,
 		appConfig.Database.User,
@@ -93,10 +86,37 @@ func main() {
 	settingsRepository := repositories.NewSystemSettingsRepository(db)
 	mailService := services.NewMailService(appConfig.Email, settingsRepository)
 
+	geocoding.SetRegionCountry(appConfig.Google.Country)
+	geocodingCacheRepository := repositories.NewGeocodingCacheRepository(db)
+	postalCodes := loadPostalCodes()
+	geocoder, err := createGeocoder(geocodingCacheRepository)
+	if err != nil {
+		logrus.WithError(err).Fatal("Error creating geocoder")
+		os.Exit(1)
+	}
+
+	// postal codes are only resolved from the local dataset for searches, centers are always geocoded by the providers
+	searchGeocoder := geocoder
+	if postalCodes != nil {
+		searchGeocoder = geocoding.NewFallbackGeocoder(postalCodes, geocoder)
+	}
+
 	centersRepository := repositories.NewCentersRepository(db)
+	if appConfig.CentersCache.Enabled {
+		centersCache := repositories.NewCentersCache(centersRepository, appConfig.CentersCache)
+		go centersCache.RefreshScheduler()
+		centersRepository = centersCache
+	}
 	operatorsRepository := repositories.NewOperatorsRepository(db)
 	operatorsService := services.NewOperatorsService(operatorsRepository, appConfig.Operators, mailService)
-	centersService := services.NewCentersService(centersRepository, appConfig.Centers, operatorsRepository, operatorsService, geocoder, mailService)
+	geocodingJobsRepository := repositories.NewGeocodingJobsRepository(db)
+	importProfilesRepository := repositories.NewImportProfilesRepository(db)
+	importProfilesService := services.NewImportProfilesService(importProfilesRepository, operatorsService)
+	plausibility := services.NewPlausibility(appConfig.Plausibility, postalCodes)
+	importRecordsRepository := repositories.NewImportRecordsRepository(db)
+	centersService := services.NewCentersService(centersRepository, geocodingJobsRepository, importRecordsRepository, appConfig.Centers, operatorsRepository, operatorsService, geocoder, mailService)
+	importJobsRepository := repositories.NewImportJobsRepository(db)
+	importJobsService := services.NewImportJobsService(appConfig.ImportJobs, importJobsRepository, centersService, operatorsService)
 
 	bugReportsRepository := repositories.NewBugReportsRepository(db)
 	bugReportsService := services.NewBugReportsService(appConfig.BugReports,
@@ -117,8 +137,9 @@ func main() {
 	router.Use(middleware.DefaultLogger)
 	router.Handle("/metrics", initMetricsHandler(centersRepository, operatorsRepository))
 	router.Mount("/api/statistics", api.NewStatisticsAPI(bugReportsRepository, tokenAuth))
-	router.Mount("/api/centers", api.NewCentersAPI(centersService, centersRepository, bugReportsService, operatorsService, geocoder, tokenAuth))
-	router.Mount("/api/operators", api.NewOperatorsAPI(operatorsRepository, operatorsService, tokenAuth))
+	router.Mount("/api/centers", api.NewCentersAPI(centersService, centersRepository, bugReportsService, operatorsService, operatorsRepository, searchGeocoder, geocodingCacheRepository, plausibility, importProfilesService, importJobsService, importRecordsRepository, appConfig.Caching, tokenAuth))
+	router.Mount("/api/operators", api.NewOperatorsAPI(operatorsRepository, operatorsService, importProfilesRepository, appConfig.Caching, tokenAuth))
+	router.Mount("/api/import-profiles", api.NewImportProfilesAPI(importProfilesRepository, importProfilesService, tokenAuth))
 
 	server := &http.Server{
 		Addr:    appConfig.Server.Listen,
@@ -128,8 +149,8 @@ func main() {
 	}
 
 	serverWaitHandle := &sync.WaitGroup{}
+	serverWaitHandle.Add(1)
 	go func() {
-		serverWaitHandle.Add(1)
 		logrus.WithFields(logrus.Fields{"listen": server.Addr}).Info("Start listening for connections")
 		err := server.ListenAndServe()
 		if err != nil && err != http.ErrServerClosed {
@@ -139,6 +160,8 @@ func main() {
 	}()
 
 	go bugReportsService.PublishScheduler()
+	go services.NewGeocodingWorker(appConfig.Geocoding.Worker, geocodingJobsRepository, centersService).GeocodingScheduler()
+	go importJobsService.ImportScheduler()
 	//go operatorsService.OperatorNotificationScheduler()
 	//go centersService.CenterNotificationScheduler()
 
@@ -154,3 +177,48 @@ func main() {
 	serverWaitHandle.Wait()
 	logrus.Info("Application stopped")
 }
+
+// loadPostalCodes loads the dataset of the offline geocoder, it returns nil if the dataset is not available
+func loadPostalCodes() geocoding.Geocoder {
+	if appConfig.Geocoding.PostalCodesFile == "" {
+		return nil
+	}
+
+	postalCodes, err := geocoding.LoadPostalCodeGeocoder(appConfig.Geocoding.PostalCodesFile)
+	if err != nil {
+		logrus.WithError(err).Warn("Error loading postal codes, skipping offline geocoding")
+		return nil
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"count": postalCodes.Len(),
+	}).Info("Loaded postal codes for offline geocoding")
+	return postalCodes
+}
+
+// createGeocoder creates the configured geocoding providers, which are used in the configured order.
+// Providers which could not be created are skipped. The results of the providers are cached.
+func createGeocoder(cache geocoding.ResultCache) (geocoding.Geocoder, error) {
+	geocoders := make([]geocoding.Geocoder, 0)
+	for _, provider := range appConfig.Geocoding.Providers {
+		switch provider {
+		case "google":
+			if googleGeocoder, err := geocoding.NewGoogleGeocoder(appConfig.Google); err == nil {
+				geocoders = append(geocoders, googleGeocoder)
+			} else {
+				logrus.WithError(err).Warn("Error creating google geocoder, skipping provider")
+			}
+		case "nominatim":
+			geocoders = append(geocoders, geocoding.NewNominatimGeocoder(appConfig.Geocoding.Nominatim))
+		default:
+			return nil, fmt.Errorf("unknown geocoding provider %s", provider)
+		}
+	}
+
+	if len(geocoders) == 0 {
+		return nil, errors.New("no geocoding provider available")
+	}
+	return geocoding.NewCachingGeocoder(geocoding.NewFallbackGeocoder(geocoders...), cache,
+		time.Duration(appConfig.Geocoding.CacheTTL)*24*time.Hour,
+		time.Duration(appConfig.Geocoding.NegativeCacheTTL)*time.Hour), nil
+}
@@ -35,8 +35,25 @@ import (
 
 type HandlerFunc func(w http.ResponseWriter, r *http.Request) (interface{}, error)
 
+// StatusResponse is returned by handlers responding with another status than 200
+type StatusResponse struct {
+	Status int
+	Body   interface{}
+}
+
 // Handle handles incoming requests and encapsulates response marshalling and error handling
 func Handle(handler HandlerFunc) http.HandlerFunc {
+	return handle(handler, false)
+}
+
+// HandleCacheable handles incoming requests like Handle, but supports conditional GET requests
+// using the ETag and the Last-Modified header of the response, see WriteContent.
+// It should only be used for public endpoints, whose responses don't depend on the user.
+func HandleCacheable(handler HandlerFunc) http.HandlerFunc {
+	return handle(handler, true)
+}
+
+func handle(handler HandlerFunc, cacheable bool) http.HandlerFunc {
 	return func(writer http.ResponseWriter, request *http.Request) {
 		response, err := handler(writer, request)
 		if err != nil {
@@ -48,13 +65,24 @@ func Handle(handler HandlerFunc) http.HandlerFunc {
 				"status": http.StatusNoContent,
 			}).Debug("Handled API request")
 			WriteResponse(writer, http.StatusNoContent, response)
+		} else if statusResponse, isStatusResponse := response.(StatusResponse); isStatusResponse {
+			logrus.WithFields(logrus.Fields{
+				"path":   request.URL.Path,
+				"query":  request.URL.RawQuery,
+				"status": statusResponse.Status,
+			}).Debug("Handled API request")
+			WriteResponse(writer, statusResponse.Status, statusResponse.Body)
 		} else {
 			logrus.WithFields(logrus.Fields{
 				"path":   request.URL.Path,
 				"query":  request.URL.RawQuery,
 				"status": http.StatusOK,
 			}).Debug("Handled API request")
-			WriteResponse(writer, http.StatusOK, response)
+			if cacheable && (request.Method == http.MethodGet || request.Method == http.MethodHead) {
+				writeCacheableResponse(writer, request, response)
+			} else {
+				WriteResponse(writer, http.StatusOK, response)
+			}
 		}
 
 	}
@@ -87,11 +115,16 @@ func WriteError(writer http.ResponseWriter, request *http.Request, err error) {
 		"query":  request.URL.RawQuery,
 		"status": status,
 	}).WithError(err).Error("Error handling API request")
+	writer.Header().Set("Content-Type", "application/json")
 	WriteResponse(writer, status, response)
 }
 
+// WriteResponse writes the body as json using the given status code.
+// The content type defaults to application/json, if it was not set before.
 func WriteResponse(writer http.ResponseWriter, code int, body interface{}) {
-	writer.Header().Add("Content-type", "application/json")
+	if writer.Header().Get("Content-Type") == "" {
+		writer.Header().Set("Content-Type", "application/json")
+	}
 	writer.WriteHeader(code)
 	if body != nil {
 		responseBody, _ := json.Marshal(body)
@@ -101,6 +134,16 @@ func WriteResponse(writer http.ResponseWriter, code int, body interface{}) {
 	}
 }
 
+// writeCacheableResponse writes the body as json supporting conditional requests, see WriteContent
+func writeCacheableResponse(writer http.ResponseWriter, request *http.Request, body interface{}) {
+	responseBody, err := json.Marshal(body)
+	if err != nil {
+		WriteError(writer, request, err)
+		return
+	}
+	WriteContent(writer, request, "application/json", responseBody)
+}
+
 // ParseRequestBody parses the request body, unmarshals the json into target and validates it
 func ParseRequestBody(r *http.Request, validate *validator.Validate, target interface{}) error {
 	if data, err := io.ReadAll(r.Body); err == nil {
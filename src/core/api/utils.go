@@ -22,8 +22,10 @@
 package api
 
 import (
+	"mime"
 	"net/http"
 	"strconv"
+	"strings"
 )
 
 func GetFloatParameter(r *http.Request, name string) (float64, bool, error) {
@@ -38,3 +40,42 @@ func GetFloatParameter(r *http.Request, name string) (float64, bool, error) {
 		return 0, false, err
 	}
 }
+
+func GetIntParameter(r *http.Request, name string) (int, bool, error) {
+	values, ok := r.URL.Query()[name]
+	if !ok {
+		return 0, false, nil
+	}
+
+	if value, err := strconv.Atoi(values[0]); err == nil {
+		return value, true, nil
+	} else {
+		return 0, false, err
+	}
+}
+
+// GetStringsParameter returns all values of the given parameter.
+// The parameter may be repeated and each value may contain a comma separated list, empty values are skipped.
+func GetStringsParameter(r *http.Request, name string) []string {
+	result := make([]string, 0)
+	for _, value := range r.URL.Query()[name] {
+		for _, entry := range strings.Split(value, ",") {
+			if entry = strings.TrimSpace(entry); entry != "" {
+				result = append(result, entry)
+			}
+		}
+	}
+	return result
+}
+
+// AcceptsMediaType reports if the Accept header of the request explicitly contains the given media type
+func AcceptsMediaType(r *http.Request, mediaType string) bool {
+	for _, header := range r.Header.Values("Accept") {
+		for _, entry := range strings.Split(header, ",") {
+			if accepted, _, err := mime.ParseMediaType(strings.TrimSpace(entry)); err == nil && accepted == mediaType {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+/*
+ *   Corona-Warn-App / cwa-map-backend
+ *
+ *   (C) 2020, T-Systems International GmbH
+ *
+ *   Deutsche Telekom AG and all other contributors /
+ *   copyright owners license this file to you under the Apache
+ *   License, Version 2.0 (the "License"); you may not use this
+ *   file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing,
+ *   software distributed under the License is distributed on an
+ *   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *   KIND, either express or implied.  See the License for the
+ *   specific language governing permissions and limitations
+ *   under the License.
+ */
+
+package mvt
+
+import (
+	"errors"
+	"math"
+)
+
+// MaxZoom is the highest supported zoom level
+const MaxZoom = 22
+
+var ErrInvalidTile = errors.New("invalid tile coordinates")
+
+// TileID identifies a tile of the web mercator tiling scheme.
+// The origin is the north-west corner of the world.
+type TileID struct {
+	Zoom uint32
+	X    uint32
+	Y    uint32
+}
+
+func NewTileID(zoom, x, y uint32) (TileID, error) {
+	if zoom > MaxZoom || x >= 1<<zoom || y >= 1<<zoom {
+		return TileID{}, ErrInvalidTile
+	}
+	return TileID{Zoom: zoom, X: x, Y: y}, nil
+}
+
+// Bounds returns the geographical bounds of the tile.
+// The bounds are enlarged by buffer, given as fraction of the tile size.
+func (t TileID) Bounds(buffer float64) (minLongitude, minLatitude, maxLongitude, maxLatitude float64) {
+	minLongitude = tileToLongitude(float64(t.X)-buffer, t.Zoom)
+	maxLongitude = tileToLongitude(float64(t.X+1)+buffer, t.Zoom)
+	minLatitude = tileToLatitude(float64(t.Y+1)+buffer, t.Zoom)
+	maxLatitude = tileToLatitude(float64(t.Y)-buffer, t.Zoom)
+	return
+}
+
+// Project converts the given geographical coordinates to the coordinates within the tile
+func (t TileID) Project(longitude, latitude float64, extent uint32) (int32, int32) {
+	n := float64(uint64(1) << t.Zoom)
+	latitudeRad := latitude * math.Pi / 180
+
+	x := ((longitude+180)/360*n - float64(t.X)) * float64(extent)
+	y := ((1-math.Log(math.Tan(latitudeRad)+1/math.Cos(latitudeRad))/math.Pi)/2*n - float64(t.Y)) * float64(extent)
+	return int32(math.Round(x)), int32(math.Round(y))
+}
+
+func tileToLongitude(x float64, zoom uint32) float64 {
+	return x/float64(uint64(1)<<zoom)*360 - 180
+}
+
+func tileToLatitude(y float64, zoom uint32) float64 {
+	n := math.Pi - 2*math.Pi*y/float64(uint64(1)<<zoom)
+	return math.Atan(math.Sinh(n)) * 180 / math.Pi
+}
@@ -0,0 +1,216 @@
+/*
+ *   Corona-Warn-App / cwa-map-backend
+ *
+ *   (C) 2020, T-Systems International GmbH
+ *
+ *   Deutsche Telekom AG and all other contributors /
+ *   copyright owners license this file to you under the Apache
+ *   License, Version 2.0 (the "License"); you may not use this
+ *   file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing,
+ *   software distributed under the License is distributed on an
+ *   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *   KIND, either express or implied.  See the License for the
+ *   specific language governing permissions and limitations
+ *   under the License.
+ */
+
+// Package mvt provides a minimal encoder for Mapbox Vector Tiles (version 2.1),
+// supporting point features only.
+package mvt
+
+import (
+	"google.golang.org/protobuf/encoding/protowire"
+	"math"
+	"sort"
+)
+
+const (
+	// ContentType is the media type of encoded vector tiles
+	ContentType = "application/vnd.mapbox-vector-tile"
+
+	// DefaultExtent is the default count of coordinate units per tile axis
+	DefaultExtent = 4096
+
+	specVersion = 2
+
+	geometryTypePoint = 1
+	commandMoveTo     = 1
+)
+
+// protobuf field numbers as defined by the vector tile specification
+const (
+	tileLayers = 3
+
+	layerName     = 1
+	layerFeatures = 2
+	layerKeys     = 3
+	layerValues   = 4
+	layerExtent   = 5
+	layerVersion  = 15
+
+	featureTags     = 2
+	featureType     = 3
+	featureGeometry = 4
+
+	valueString = 1
+	valueDouble = 3
+	valueInt    = 4
+	valueUint   = 5
+	valueBool   = 7
+)
+
+// Tile is a vector tile consisting of multiple layers
+type Tile struct {
+	Layers []*Layer
+}
+
+// Layer is a named layer of a vector tile.
+// Keys and values of the feature properties are deduplicated within the layer.
+type Layer struct {
+	Name     string
+	Extent   uint32
+	features [][]byte
+	keys     []string
+	keyIndex map[string]uint32
+	values   [][]byte
+	valIndex map[string]uint32
+}
+
+func NewLayer(name string, extent uint32) *Layer {
+	return &Layer{
+		Name:     name,
+		Extent:   extent,
+		keyIndex: make(map[string]uint32),
+		valIndex: make(map[string]uint32),
+	}
+}
+
+// AddPoint adds a point feature with the given properties to the layer.
+// x and y are tile coordinates within the extent of the layer, the origin is the upper left corner.
+// Supported property types are string, bool, int, int64, uint, uint64 and float64, other values and nil are skipped.
+func (l *Layer) AddPoint(x, y int32, properties map[string]interface{}) {
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	tags := make([]byte, 0)
+	for _, name := range names {
+		value, ok := encodeValue(properties[name])
+		if !ok {
+			continue
+		}
+		tags = protowire.AppendVarint(tags, uint64(l.keyID(name)))
+		tags = protowire.AppendVarint(tags, uint64(l.valueID(value)))
+	}
+
+	geometry := protowire.AppendVarint(nil, uint64(commandMoveTo&0x7|1<<3))
+	geometry = protowire.AppendVarint(geometry, protowire.EncodeZigZag(int64(x)))
+	geometry = protowire.AppendVarint(geometry, protowire.EncodeZigZag(int64(y)))
+
+	var feature []byte
+	if len(tags) > 0 {
+		feature = protowire.AppendTag(feature, featureTags, protowire.BytesType)
+		feature = protowire.AppendBytes(feature, tags)
+	}
+	feature = protowire.AppendTag(feature, featureType, protowire.VarintType)
+	feature = protowire.AppendVarint(feature, geometryTypePoint)
+	feature = protowire.AppendTag(feature, featureGeometry, protowire.BytesType)
+	feature = protowire.AppendBytes(feature, geometry)
+
+	l.features = append(l.features, feature)
+}
+
+// Len returns the count of features in the layer
+func (l *Layer) Len() int {
+	return len(l.features)
+}
+
+func (l *Layer) keyID(key string) uint32 {
+	if id, ok := l.keyIndex[key]; ok {
+		return id
+	}
+	id := uint32(len(l.keys))
+	l.keys = append(l.keys, key)
+	l.keyIndex[key] = id
+	return id
+}
+
+func (l *Layer) valueID(value []byte) uint32 {
+	if id, ok := l.valIndex[string(value)]; ok {
+		return id
+	}
+	id := uint32(len(l.values))
+	l.values = append(l.values, value)
+	l.valIndex[string(value)] = id
+	return id
+}
+
+func (l *Layer) marshal() []byte {
+	var b []byte
+	b = protowire.AppendTag(b, layerVersion, protowire.VarintType)
+	b = protowire.AppendVarint(b, specVersion)
+	b = protowire.AppendTag(b, layerName, protowire.BytesType)
+	b = protowire.AppendString(b, l.Name)
+	for _, feature := range l.features {
+		b = protowire.AppendTag(b, layerFeatures, protowire.BytesType)
+		b = protowire.AppendBytes(b, feature)
+	}
+	for _, key := range l.keys {
+		b = protowire.AppendTag(b, layerKeys, protowire.BytesType)
+		b = protowire.AppendString(b, key)
+	}
+	for _, value := range l.values {
+		b = protowire.AppendTag(b, layerValues, protowire.BytesType)
+		b = protowire.AppendBytes(b, value)
+	}
+	b = protowire.AppendTag(b, layerExtent, protowire.VarintType)
+	b = protowire.AppendVarint(b, uint64(l.Extent))
+	return b
+}
+
+// Marshal encodes the tile using the protobuf encoding
+func (t *Tile) Marshal() []byte {
+	var b []byte
+	for _, layer := range t.Layers {
+		b = protowire.AppendTag(b, tileLayers, protowire.BytesType)
+		b = protowire.AppendBytes(b, layer.marshal())
+	}
+	return b
+}
+
+func encodeValue(value interface{}) ([]byte, bool) {
+	var b []byte
+	switch v := value.(type) {
+	case string:
+		b = protowire.AppendTag(b, valueString, protowire.BytesType)
+		b = protowire.AppendString(b, v)
+	case bool:
+		b = protowire.AppendTag(b, valueBool, protowire.VarintType)
+		b = protowire.AppendVarint(b, protowire.EncodeBool(v))
+	case int:
+		b = protowire.AppendTag(b, valueInt, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(v))
+	case int64:
+		b = protowire.AppendTag(b, valueInt, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(v))
+	case uint:
+		b = protowire.AppendTag(b, valueUint, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(v))
+	case uint64:
+		b = protowire.AppendTag(b, valueUint, protowire.VarintType)
+		b = protowire.AppendVarint(b, v)
+	case float64:
+		b = protowire.AppendTag(b, valueDouble, protowire.Fixed64Type)
+		b = protowire.AppendFixed64(b, math.Float64bits(v))
+	default:
+		return nil, false
+	}
+	return b, true
+}
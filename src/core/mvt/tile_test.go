@@ -0,0 +1,229 @@
+/*
+ *   Corona-Warn-App / cwa-map-backend
+ *
+ *   (C) 2020, T-Systems International GmbH
+ *
+ *   Deutsche Telekom AG and all other contributors /
+ *   copyright owners license this file to you under the Apache
+ *   License, Version 2.0 (the "License"); you may not use this
+ *   file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing,
+ *   software distributed under the License is distributed on an
+ *   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *   KIND, either express or implied.  See the License for the
+ *   specific language governing permissions and limitations
+ *   under the License.
+ */
+
+package mvt
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+	"math"
+	"testing"
+)
+
+// field is a decoded protobuf field, value is either the varint, the fixed64 or the bytes of the field
+type field struct {
+	number protowire.Number
+	value  interface{}
+}
+
+// decodeMessage decodes all fields of the protobuf message
+func decodeMessage(t *testing.T, data []byte) []field {
+	fields := make([]field, 0)
+	for len(data) > 0 {
+		number, wireType, n := protowire.ConsumeTag(data)
+		require.GreaterOrEqual(t, n, 0, "invalid tag")
+		data = data[n:]
+
+		var value interface{}
+		switch wireType {
+		case protowire.VarintType:
+			value, n = protowire.ConsumeVarint(data)
+		case protowire.Fixed64Type:
+			value, n = protowire.ConsumeFixed64(data)
+		case protowire.BytesType:
+			value, n = protowire.ConsumeBytes(data)
+		default:
+			t.Fatalf("unexpected wire type %d", wireType)
+		}
+		require.GreaterOrEqual(t, n, 0, "invalid value of field %d", number)
+		data = data[n:]
+		fields = append(fields, field{number: number, value: value})
+	}
+	return fields
+}
+
+// decodePacked decodes a packed repeated uint32 field
+func decodePacked(t *testing.T, data []byte) []uint64 {
+	values := make([]uint64, 0)
+	for len(data) > 0 {
+		value, n := protowire.ConsumeVarint(data)
+		require.GreaterOrEqual(t, n, 0, "invalid packed value")
+		values = append(values, value)
+		data = data[n:]
+	}
+	return values
+}
+
+// decodedLayer is a layer decoded from a tile
+type decodedLayer struct {
+	version  uint64
+	name     string
+	extent   uint64
+	keys     []string
+	values   []interface{}
+	features []decodedFeature
+}
+
+type decodedFeature struct {
+	geometryType uint64
+	geometry     []uint64
+	properties   map[string]interface{}
+}
+
+func decodeLayers(t *testing.T, data []byte) []decodedLayer {
+	layers := make([]decodedLayer, 0)
+	for _, tileField := range decodeMessage(t, data) {
+		require.Equal(t, protowire.Number(tileLayers), tileField.number)
+
+		layer := decodedLayer{}
+		features := make([][]byte, 0)
+		for _, f := range decodeMessage(t, tileField.value.([]byte)) {
+			switch f.number {
+			case layerVersion:
+				layer.version = f.value.(uint64)
+			case layerName:
+				layer.name = string(f.value.([]byte))
+			case layerExtent:
+				layer.extent = f.value.(uint64)
+			case layerKeys:
+				layer.keys = append(layer.keys, string(f.value.([]byte)))
+			case layerValues:
+				layer.values = append(layer.values, decodeValue(t, f.value.([]byte)))
+			case layerFeatures:
+				features = append(features, f.value.([]byte))
+			default:
+				t.Fatalf("unexpected layer field %d", f.number)
+			}
+		}
+
+		for _, data := range features {
+			layer.features = append(layer.features, decodeFeature(t, data, layer))
+		}
+		layers = append(layers, layer)
+	}
+	return layers
+}
+
+func decodeFeature(t *testing.T, data []byte, layer decodedLayer) decodedFeature {
+	feature := decodedFeature{properties: make(map[string]interface{})}
+	for _, f := range decodeMessage(t, data) {
+		switch f.number {
+		case featureType:
+			feature.geometryType = f.value.(uint64)
+		case featureGeometry:
+			feature.geometry = decodePacked(t, f.value.([]byte))
+		case featureTags:
+			tags := decodePacked(t, f.value.([]byte))
+			require.Equal(t, 0, len(tags)%2, "tags must be pairs of keys and values")
+			for i := 0; i < len(tags); i += 2 {
+				require.Less(t, int(tags[i]), len(layer.keys))
+				require.Less(t, int(tags[i+1]), len(layer.values))
+				feature.properties[layer.keys[tags[i]]] = layer.values[tags[i+1]]
+			}
+		default:
+			t.Fatalf("unexpected feature field %d", f.number)
+		}
+	}
+	return feature
+}
+
+func decodeValue(t *testing.T, data []byte) interface{} {
+	fields := decodeMessage(t, data)
+	require.Len(t, fields, 1, "a value must have exactly one field")
+
+	switch fields[0].number {
+	case valueString:
+		return string(fields[0].value.([]byte))
+	case valueDouble:
+		return math.Float64frombits(fields[0].value.(uint64))
+	case valueInt:
+		return int64(fields[0].value.(uint64))
+	case valueUint:
+		return fields[0].value.(uint64)
+	case valueBool:
+		return protowire.DecodeBool(fields[0].value.(uint64))
+	}
+	t.Fatalf("unexpected value field %d", fields[0].number)
+	return nil
+}
+
+func TestTileRoundTrip(t *testing.T) {
+	layer := NewLayer("centers", DefaultExtent)
+	layer.AddPoint(25, 17, map[string]interface{}{
+		"name":     "Testcenter",
+		"dcc":      true,
+		"count":    -3,
+		"id":       uint64(7),
+		"distance": 1.5,
+		"note":     nil,
+	})
+	layer.AddPoint(-4, 4100, map[string]interface{}{
+		"name": "Testcenter",
+		"dcc":  false,
+	})
+	assert.Equal(t, 2, layer.Len())
+
+	layers := decodeLayers(t, (&Tile{Layers: []*Layer{layer}}).Marshal())
+	require.Len(t, layers, 1)
+
+	decoded := layers[0]
+	assert.Equal(t, uint64(specVersion), decoded.version)
+	assert.Equal(t, "centers", decoded.name)
+	assert.Equal(t, uint64(DefaultExtent), decoded.extent)
+
+	// keys and values are deduplicated, nil values are skipped
+	assert.ElementsMatch(t, []string{"count", "dcc", "distance", "id", "name"}, decoded.keys)
+	assert.ElementsMatch(t, []interface{}{int64(-3), true, false, 1.5, uint64(7), "Testcenter"}, decoded.values)
+
+	require.Len(t, decoded.features, 2)
+	assert.Equal(t, uint64(geometryTypePoint), decoded.features[0].geometryType)
+	assert.Equal(t, map[string]interface{}{
+		"name":     "Testcenter",
+		"dcc":      true,
+		"count":    int64(-3),
+		"id":       uint64(7),
+		"distance": 1.5,
+	}, decoded.features[0].properties)
+	assert.Equal(t, map[string]interface{}{
+		"name": "Testcenter",
+		"dcc":  false,
+	}, decoded.features[1].properties)
+
+	// a single MoveTo command followed by the zigzag encoded coordinates
+	for i, point := range [][2]int64{{25, 17}, {-4, 4100}} {
+		geometry := decoded.features[i].geometry
+		require.Len(t, geometry, 3)
+		assert.Equal(t, uint64(commandMoveTo), geometry[0]&0x7, "command")
+		assert.Equal(t, uint64(1), geometry[0]>>3, "command count")
+		assert.Equal(t, point[0], protowire.DecodeZigZag(geometry[1]))
+		assert.Equal(t, point[1], protowire.DecodeZigZag(geometry[2]))
+	}
+}
+
+func TestTileWithoutFeatures(t *testing.T) {
+	layers := decodeLayers(t, (&Tile{Layers: []*Layer{NewLayer("empty", 512)}}).Marshal())
+	require.Len(t, layers, 1)
+	assert.Equal(t, "empty", layers[0].name)
+	assert.Equal(t, uint64(512), layers[0].extent)
+	assert.Empty(t, layers[0].features)
+	assert.Empty(t, layers[0].keys)
+}
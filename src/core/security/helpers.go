@@ -62,3 +62,18 @@ func HasRole(ctx context.Context, value string) bool {
 	}
 	return false
 }
+
+// GetUsername returns the preferred username of the authenticated user, or the subject if it is missing
+func GetUsername(ctx context.Context) string {
+	token, err := GetTokenFromContext(ctx)
+	if err != nil {
+		return ""
+	}
+
+	if entry, ok := token.Get("preferred_username"); ok {
+		if username, ok := entry.(string); ok && username != "" {
+			return username
+		}
+	}
+	return token.Subject()
+}
@@ -96,7 +96,7 @@ type Center struct {
 	AddressNote  *string
 	OpeningHours pq.StringArray   `gorm:"type:varchar(64)[]" validate:"dive,max=64"`
 	Appointment  *AppointmentType `validate:"omitempty,oneof=Required NotRequired Possible"`
-	TestKinds    pq.StringArray   `gorm:"type:varchar(32)[]"`
+	TestKinds    pq.StringArray   `gorm:"type:varchar(32)[]" validate:"dive,oneof=Antigen PCR Vaccination Antibody"`
 	EnterDate    *time.Time
 	LeaveDate    *time.Time
 	DCC          *bool
@@ -108,6 +108,16 @@ type Center struct {
 	Visible      *bool
 	LastUpdate   *time.Time
 	Notified     *time.Time
+
+	// OpeningSchedule contains the structured opening hours, parsed from OpeningHours
+	OpeningSchedule OpeningSchedule `gorm:"type:jsonb"`
+
+	// GeocodingStatus is the state of the geocoding of the address,
+	// the other geocoding fields contain the details of the last result
+	GeocodingStatus       *GeocodingStatus
+	GeocodingLocationType *GeocodingLocationType
+	GeocodingAddress      *string
+	GeocodingProvider     *string
 }
 
 type CenterWithDistance struct {
@@ -0,0 +1,76 @@
+/*
+ *   Corona-Warn-App / cwa-map-backend
+ *
+ *   (C) 2020, T-Systems International GmbH
+ *
+ *   Deutsche Telekom AG and all other contributors /
+ *   copyright owners license this file to you under the Apache
+ *   License, Version 2.0 (the "License"); you may not use this
+ *   file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing,
+ *   software distributed under the License is distributed on an
+ *   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *   KIND, either express or implied.  See the License for the
+ *   specific language governing permissions and limitations
+ *   under the License.
+ */
+
+package domain
+
+import "time"
+
+// GeocodingCacheEntry is a persisted geocoding result for a normalized address
+type GeocodingCacheEntry struct {
+	Address string `gorm:"primaryKey"`
+	Status  string
+	Result  *string `gorm:"type:jsonb"`
+	Created time.Time
+}
+
+// GeocodingJob is a pending geocoding of a center
+type GeocodingJob struct {
+	CenterUUID  string `gorm:"primaryKey"`
+	Attempts    int
+	NextAttempt time.Time
+	LockedUntil *time.Time
+	LastError   *string
+	Created     time.Time
+}
+
+// GeocodingStatus is the state of the geocoding of a center
+type GeocodingStatus string
+
+const (
+	// GeocodingPending is the state of centers waiting for geocoding
+	GeocodingPending GeocodingStatus = "pending"
+
+	// GeocodingOk is the state of centers with a unique geocoding result
+	GeocodingOk GeocodingStatus = "ok"
+
+	// GeocodingAmbiguous is the state of centers whose address has multiple results
+	GeocodingAmbiguous GeocodingStatus = "ambiguous"
+
+	// GeocodingFailed is the state of centers whose address could not be resolved
+	GeocodingFailed GeocodingStatus = "failed"
+
+	// GeocodingManual is the state of centers with coordinates set by the operator
+	GeocodingManual GeocodingStatus = "manual"
+)
+
+// GeocodingLocationType describes the accuracy of a geocoding result
+type GeocodingLocationType string
+
+const (
+	// LocationRooftop is a result exactly matching the building
+	LocationRooftop GeocodingLocationType = "rooftop"
+
+	// LocationInterpolated is a result interpolated between two points of a street
+	LocationInterpolated GeocodingLocationType = "interpolated"
+
+	// LocationApproximate is a result only matching the street, postal code or city
+	LocationApproximate GeocodingLocationType = "approximate"
+)
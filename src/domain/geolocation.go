@@ -21,6 +21,8 @@
 
 package domain
 
+import "math"
+
 type Bounds struct {
 	NorthEast Coordinates
 	SouthWest Coordinates
@@ -31,3 +33,12 @@ type Coordinates struct {
 	Latitude  float64
 	Fixed     bool
 }
+
+// DistanceTo returns the distance in km to the given coordinates, like the haversine function of the database
+func (c Coordinates) DistanceTo(other Coordinates) float64 {
+	dLat := (other.Latitude - c.Latitude) * math.Pi / 180
+	dLng := (other.Longitude - c.Longitude) * math.Pi / 180
+	a := math.Pow(math.Sin(dLat/2), 2) +
+		math.Pow(math.Sin(dLng/2), 2)*math.Cos(c.Latitude*math.Pi/180)*math.Cos(other.Latitude*math.Pi/180)
+	return math.Asin(math.Sqrt(a)) * 12742
+}
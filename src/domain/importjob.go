@@ -0,0 +1,140 @@
+/*
+ *   Corona-Warn-App / cwa-map-backend
+ *
+ *   (C) 2020, T-Systems International GmbH
+ *
+ *   Deutsche Telekom AG and all other contributors /
+ *   copyright owners license this file to you under the Apache
+ *   License, Version 2.0 (the "License"); you may not use this
+ *   file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing,
+ *   software distributed under the License is distributed on an
+ *   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *   KIND, either express or implied.  See the License for the
+ *   specific language governing permissions and limitations
+ *   under the License.
+ */
+
+package domain
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// ImportJobStatus is the state of an import job
+type ImportJobStatus string
+
+const (
+	// ImportJobPending is the state of jobs waiting for a worker
+	ImportJobPending ImportJobStatus = "pending"
+
+	// ImportJobRunning is the state of jobs currently processed by a worker
+	ImportJobRunning ImportJobStatus = "running"
+
+	// ImportJobCompleted is the state of finished jobs, failed centers of partial imports are listed in the errors
+	ImportJobCompleted ImportJobStatus = "completed"
+
+	// ImportJobFailed is the state of jobs, which did not import any center
+	ImportJobFailed ImportJobStatus = "failed"
+)
+
+// ImportJobError is the error of a single center of an import job
+type ImportJobError struct {
+	// Index is the index of the center within the job
+	Index int    `json:"index"`
+	Error string `json:"error"`
+}
+
+// ImportJobErrors contains the errors of the centers of an import job
+type ImportJobErrors []ImportJobError
+
+func (e ImportJobErrors) Value() (driver.Value, error) {
+	if e == nil {
+		return "[]", nil
+	}
+	return json.Marshal(e)
+}
+
+func (e *ImportJobErrors) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*e = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, e)
+	case string:
+		return json.Unmarshal([]byte(v), e)
+	}
+	return fmt.Errorf("unsupported import job errors type %T", value)
+}
+
+// CenterList contains centers stored as json, like the centers of import jobs and import snapshots
+type CenterList []Center
+
+func (c CenterList) Value() (driver.Value, error) {
+	if c == nil {
+		return "[]", nil
+	}
+	return json.Marshal(c)
+}
+
+func (c *CenterList) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*c = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, c)
+	case string:
+		return json.Unmarshal([]byte(v), c)
+	}
+	return fmt.Errorf("unsupported center list type %T", value)
+}
+
+// ImportJob is an import of centers processed in the background
+type ImportJob struct {
+	UUID         string `gorm:"primaryKey"`
+	OperatorUUID string
+	Status       ImportJobStatus
+
+	// DeleteAll deletes all centers of the operator before importing the centers
+	DeleteAll bool
+
+	// Partial saves the valid centers, even if other centers fail
+	Partial bool
+
+	// SubmittedBy is the name of the user, who submitted the job
+	SubmittedBy string
+
+	// AllowDCC keeps the DCC flag of the centers, the role of the user is not available within the worker
+	AllowDCC bool `gorm:"column:allow_dcc"`
+
+	// Centers are the centers to be imported, they are not loaded for listing jobs
+	Centers CenterList `gorm:"type:jsonb"`
+
+	// Rejected are the errors of the centers, which failed the validation before submitting a partial import.
+	// These centers are not contained in Centers, but they are included in the counters and errors.
+	Rejected ImportJobErrors `gorm:"type:jsonb"`
+
+	// Total is the count of centers, Processed the count of centers saved or failed so far
+	Total     int
+	Processed int
+	Succeeded int
+	Failed    int
+
+	// Errors contains the errors of the failed centers, Error the error which failed the whole job
+	Errors ImportJobErrors `gorm:"type:jsonb"`
+	Error  *string
+
+	Created     time.Time
+	Started     *time.Time
+	Finished    *time.Time
+	LockedUntil *time.Time
+}
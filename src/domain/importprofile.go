@@ -0,0 +1,109 @@
+/*
+ *   Corona-Warn-App / cwa-map-backend
+ *
+ *   (C) 2020, T-Systems International GmbH
+ *
+ *   Deutsche Telekom AG and all other contributors /
+ *   copyright owners license this file to you under the Apache
+ *   License, Version 2.0 (the "License"); you may not use this
+ *   file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing,
+ *   software distributed under the License is distributed on an
+ *   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *   KIND, either express or implied.  See the License for the
+ *   specific language governing permissions and limitations
+ *   under the License.
+ */
+
+package domain
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"github.com/lib/pq"
+)
+
+// Fields of a center, which can be mapped to the columns of an import file
+const (
+	ImportFieldPartnerId     = "partnerId"
+	ImportFieldUserReference = "userReference"
+	ImportFieldName          = "name"
+	ImportFieldOperatorName  = "operatorName"
+	ImportFieldLabId         = "labId"
+	ImportFieldStreet        = "street"
+	ImportFieldHouseNumber   = "houseNumber"
+	ImportFieldZip           = "zip"
+	ImportFieldCity          = "city"
+	ImportFieldEnterDate     = "enterDate"
+	ImportFieldLeaveDate     = "leaveDate"
+	ImportFieldEmail         = "email"
+	ImportFieldOpeningHours  = "openingHours"
+	ImportFieldAppointment   = "appointment"
+	ImportFieldTestKinds     = "testKinds"
+	ImportFieldWebsite       = "website"
+	ImportFieldDCC           = "dcc"
+	ImportFieldAddressNote   = "addressNote"
+	ImportFieldVisible       = "visible"
+	ImportFieldLatitude      = "latitude"
+	ImportFieldLongitude     = "longitude"
+)
+
+// ImportFields are all fields, which can be mapped by an import profile
+var ImportFields = []string{
+	ImportFieldPartnerId, ImportFieldUserReference, ImportFieldName, ImportFieldOperatorName, ImportFieldLabId,
+	ImportFieldStreet, ImportFieldHouseNumber, ImportFieldZip, ImportFieldCity, ImportFieldEnterDate,
+	ImportFieldLeaveDate, ImportFieldEmail, ImportFieldOpeningHours, ImportFieldAppointment, ImportFieldTestKinds,
+	ImportFieldWebsite, ImportFieldDCC, ImportFieldAddressNote, ImportFieldVisible, ImportFieldLatitude,
+	ImportFieldLongitude,
+}
+
+// RequiredImportFields are the fields, which must be mapped to a column of the import file
+var RequiredImportFields = []string{ImportFieldName, ImportFieldStreet, ImportFieldEmail}
+
+// ImportColumns maps the fields of a center to the accepted header names of their column
+type ImportColumns map[string][]string
+
+func (c ImportColumns) Value() (driver.Value, error) {
+	if c == nil {
+		return nil, nil
+	}
+	return json.Marshal(c)
+}
+
+func (c *ImportColumns) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*c = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, c)
+	case string:
+		return json.Unmarshal([]byte(v), c)
+	}
+	return fmt.Errorf("unsupported import columns type %T", value)
+}
+
+// ImportProfile describes the format of center import files
+type ImportProfile struct {
+	UUID string `gorm:"primaryKey"`
+	Name string `validate:"required,max=64"`
+
+	// Delimiter separates the fields of a row
+	Delimiter string `validate:"required,len=1"`
+
+	// HeaderRows is the count of rows before the first center, containing the column names
+	HeaderRows int `validate:"min=1,max=10"`
+
+	// DateFormats are the accepted layouts of dates, see time.Parse
+	DateFormats pq.StringArray `gorm:"type:varchar(32)[]" validate:"min=1,dive,required,max=32"`
+
+	// TrueValues are the values of boolean fields, which are read as true (ignoring case)
+	TrueValues pq.StringArray `gorm:"type:varchar(32)[]" validate:"dive,required,max=32"`
+
+	Columns ImportColumns `gorm:"type:jsonb"`
+}
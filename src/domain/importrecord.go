@@ -0,0 +1,57 @@
+/*
+ *   Corona-Warn-App / cwa-map-backend
+ *
+ *   (C) 2020, T-Systems International GmbH
+ *
+ *   Deutsche Telekom AG and all other contributors /
+ *   copyright owners license this file to you under the Apache
+ *   License, Version 2.0 (the "License"); you may not use this
+ *   file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing,
+ *   software distributed under the License is distributed on an
+ *   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *   KIND, either express or implied.  See the License for the
+ *   specific language governing permissions and limitations
+ *   under the License.
+ */
+
+package domain
+
+import (
+	"github.com/lib/pq"
+	"time"
+)
+
+// ImportRecord is an entry of the import history of an operator,
+// including a snapshot of the centers changed by the import
+type ImportRecord struct {
+	UUID         string `gorm:"primaryKey"`
+	OperatorUUID string
+
+	// ImportedBy is the name of the user, who imported the centers
+	ImportedBy string
+	Imported   time.Time
+
+	DeleteAll bool
+	Partial   bool
+
+	// Created, Updated and Deleted are the counts of centers changed by the import
+	Created int
+	Updated int
+	Deleted int
+
+	// CreatedCenters contains the uuids of the centers created by the import
+	CreatedCenters pq.StringArray `gorm:"type:varchar(36)[]"`
+
+	// Snapshot contains the updated and deleted centers like they were before the import.
+	// It is not loaded for listing the history.
+	Snapshot CenterList `gorm:"type:jsonb"`
+
+	// RolledBack is set, if the import has been rolled back to its snapshot
+	RolledBack   *time.Time
+	RolledBackBy *string
+}
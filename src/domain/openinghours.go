@@ -0,0 +1,510 @@
+/*
+ *   Corona-Warn-App / cwa-map-backend
+ *
+ *   (C) 2020, T-Systems International GmbH
+ *
+ *   Deutsche Telekom AG and all other contributors /
+ *   copyright owners license this file to you under the Apache
+ *   License, Version 2.0 (the "License"); you may not use this
+ *   file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing,
+ *   software distributed under the License is distributed on an
+ *   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *   KIND, either express or implied.  See the License for the
+ *   specific language governing permissions and limitations
+ *   under the License.
+ */
+
+package domain
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata"
+)
+
+const minutesPerDay = 24 * 60
+
+// OpeningHoursLocation is the time zone used for evaluating opening hours
+var OpeningHoursLocation = loadOpeningHoursLocation()
+
+// OpeningRule describes a single opening time range.
+// A rule applies either to weekdays, to a specific date or to public holidays.
+// Date and holiday rules replace the weekday rules for the affected days.
+type OpeningRule struct {
+	// Weekdays the rule applies to
+	Weekdays []time.Weekday `json:"weekdays,omitempty"`
+
+	// Date the rule applies to, either formatted as 2006-01-02 or as 01-02 for every year
+	Date string `json:"date,omitempty"`
+
+	// Holidays reports if the rule applies to public holidays
+	Holidays bool `json:"holidays,omitempty"`
+
+	// Closed reports if the center is closed on the selected days
+	Closed bool `json:"closed,omitempty"`
+
+	// Opens and Closes are the minutes since midnight
+	Opens  int `json:"opens"`
+	Closes int `json:"closes"`
+}
+
+// OpeningSchedule contains the structured opening hours of a center
+type OpeningSchedule []OpeningRule
+
+func (s OpeningSchedule) Value() (driver.Value, error) {
+	if s == nil {
+		return nil, nil
+	}
+	return json.Marshal(s)
+}
+
+func (s *OpeningSchedule) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*s = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, s)
+	case string:
+		return json.Unmarshal([]byte(v), s)
+	}
+	return fmt.Errorf("unsupported opening schedule type %T", value)
+}
+
+// IsOpen reports if the schedule contains an opening rule for the given time
+func (s OpeningSchedule) IsOpen(t time.Time) bool {
+	t = t.In(OpeningHoursLocation)
+	minute := t.Hour()*60 + t.Minute()
+	dateKeys := OpeningDateKeys(t)
+
+	rules := s.filter(func(rule OpeningRule) bool {
+		return rule.Date == dateKeys[0] || rule.Date == dateKeys[1]
+	})
+	if len(rules) == 0 && IsPublicHoliday(t) {
+		rules = s.filter(func(rule OpeningRule) bool {
+			return rule.Holidays
+		})
+	}
+	if len(rules) == 0 {
+		rules = s.filter(func(rule OpeningRule) bool {
+			for _, weekday := range rule.Weekdays {
+				if weekday == t.Weekday() {
+					return true
+				}
+			}
+			return false
+		})
+	}
+
+	for _, rule := range rules {
+		if !rule.Closed && rule.Opens <= minute && rule.Closes > minute {
+			return true
+		}
+	}
+	return false
+}
+
+func (s OpeningSchedule) filter(predicate func(rule OpeningRule) bool) []OpeningRule {
+	result := make([]OpeningRule, 0)
+	for _, rule := range s {
+		if predicate(rule) {
+			result = append(result, rule)
+		}
+	}
+	return result
+}
+
+// OpeningDateKeys returns the keys of date rules matching the given time,
+// the key for this specific date and the key for the date in every year.
+func OpeningDateKeys(t time.Time) [2]string {
+	t = t.In(OpeningHoursLocation)
+	return [2]string{t.Format("2006-01-02"), t.Format("01-02")}
+}
+
+// IsPublicHoliday reports if the date of the given time is a nationwide public holiday in germany
+func IsPublicHoliday(t time.Time) bool {
+	t = t.In(OpeningHoursLocation)
+	year, month, day := t.Date()
+	switch {
+	case month == time.January && day == 1,
+		month == time.May && day == 1,
+		month == time.October && day == 3,
+		month == time.December && (day == 25 || day == 26):
+		return true
+	}
+
+	date := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	easter := easterSunday(year)
+	for _, offset := range []int{-2, 1, 39, 50} {
+		if date.Equal(easter.AddDate(0, 0, offset)) {
+			return true
+		}
+	}
+	return false
+}
+
+// easterSunday calculates the date of easter sunday using the anonymous gregorian algorithm
+func easterSunday(year int) time.Time {
+	a := year % 19
+	b := year / 100
+	c := year % 100
+	d := b / 4
+	e := b % 4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i := c / 4
+	k := c % 4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+}
+
+func loadOpeningHoursLocation() *time.Location {
+	location, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+// ParseOpeningHours parses the given lines of free text opening hours, like "Mo-Fr 08:00-18:00".
+// Lines which could not be parsed are skipped and reported as warnings.
+func ParseOpeningHours(lines []string) (OpeningSchedule, []string) {
+	var schedule OpeningSchedule
+	var warnings []string
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		rules, err := parseOpeningHoursLine(line)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("invalid opening hours '%s': %s", line, err.Error()))
+			continue
+		}
+		schedule = append(schedule, rules...)
+	}
+	return schedule, warnings
+}
+
+type openingToken struct {
+	kind     openingTokenKind
+	weekdays []time.Weekday
+	minutes  int
+	date     string
+	text     string
+}
+
+type openingTokenKind int
+
+const (
+	tokenWeekday openingTokenKind = iota
+	tokenDate
+	tokenHolidays
+	tokenTime
+	tokenRange
+	tokenSeparator
+	tokenClosed
+)
+
+var (
+	errMissingDays  = errors.New("missing days")
+	errMissingTimes = errors.New("missing times")
+	errInvalidTime  = errors.New("invalid time")
+
+	openingDatePattern  = regexp.MustCompile(`^(\d{1,2})\.(\d{1,2})\.(\d{4})?`)
+	openingTimePattern  = regexp.MustCompile(`^(\d{1,2})(?:[:.](\d{2}))?`)
+	openingWordPattern  = regexp.MustCompile(`^[a-zäöüß]+\.?`)
+	openingSpacePattern = regexp.MustCompile(`^[\s:]+`)
+
+	allWeekdays = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday,
+		time.Friday, time.Saturday, time.Sunday}
+
+	openingWords = map[string]openingToken{
+		"täglich":     {kind: tokenWeekday, weekdays: allWeekdays},
+		"taeglich":    {kind: tokenWeekday, weekdays: allWeekdays},
+		"tgl":         {kind: tokenWeekday, weekdays: allWeekdays},
+		"daily":       {kind: tokenWeekday, weekdays: allWeekdays},
+		"werktags":    {kind: tokenWeekday, weekdays: allWeekdays[:5]},
+		"werktäglich": {kind: tokenWeekday, weekdays: allWeekdays[:5]},
+		"weekdays":    {kind: tokenWeekday, weekdays: allWeekdays[:5]},
+		"wochenende":  {kind: tokenWeekday, weekdays: allWeekdays[5:]},
+		"wochenends":  {kind: tokenWeekday, weekdays: allWeekdays[5:]},
+		"weekend":     {kind: tokenWeekday, weekdays: allWeekdays[5:]},
+		"feiertag":    {kind: tokenHolidays},
+		"feiertage":   {kind: tokenHolidays},
+		"feiertags":   {kind: tokenHolidays},
+		"feiertagen":  {kind: tokenHolidays},
+		"holiday":     {kind: tokenHolidays},
+		"holidays":    {kind: tokenHolidays},
+		"geschlossen": {kind: tokenClosed},
+		"closed":      {kind: tokenClosed},
+		"ruhetag":     {kind: tokenClosed},
+		"bis":         {kind: tokenRange},
+		"to":          {kind: tokenRange},
+		"und":         {kind: tokenSeparator},
+		"and":         {kind: tokenSeparator},
+		"sowie":       {kind: tokenSeparator},
+	}
+
+	openingIgnoredWords = map[string]bool{
+		"uhr":  true,
+		"h":    true,
+		"von":  true,
+		"from": true,
+		"an":   true,
+		"am":   true,
+	}
+
+	openingWeekdayNames = map[time.Weekday][]string{
+		time.Monday:    {"mo", "mon", "montag", "montags", "monday"},
+		time.Tuesday:   {"di", "die", "dienstag", "dienstags", "tu", "tue", "tuesday"},
+		time.Wednesday: {"mi", "mit", "mittwoch", "mittwochs", "we", "wed", "wednesday"},
+		time.Thursday:  {"do", "don", "donnerstag", "donnerstags", "th", "thu", "thursday"},
+		time.Friday:    {"fr", "fri", "freitag", "freitags", "friday"},
+		time.Saturday:  {"sa", "sam", "samstag", "samstags", "sonnabend", "sat", "saturday"},
+		time.Sunday:    {"so", "son", "sonntag", "sonntags", "su", "sun", "sunday"},
+	}
+)
+
+func init() {
+	for weekday, names := range openingWeekdayNames {
+		for _, name := range names {
+			openingWords[name] = openingToken{kind: tokenWeekday, weekdays: []time.Weekday{weekday}}
+		}
+	}
+}
+
+// tokenizeOpeningHours splits the line into tokens, ignoring filler words like "Uhr"
+func tokenizeOpeningHours(line string) ([]openingToken, error) {
+	line = strings.ToLower(line)
+	tokens := make([]openingToken, 0)
+	for len(line) > 0 {
+		if match := openingSpacePattern.FindString(line); match != "" {
+			line = line[len(match):]
+			continue
+		}
+
+		if match := openingDatePattern.FindStringSubmatch(line); match != nil {
+			day, _ := strconv.Atoi(match[1])
+			month, _ := strconv.Atoi(match[2])
+			if day < 1 || day > 31 || month < 1 || month > 12 {
+				return nil, fmt.Errorf("invalid date '%s'", match[0])
+			}
+
+			date := fmt.Sprintf("%02d-%02d", month, day)
+			if match[3] != "" {
+				date = match[3] + "-" + date
+			}
+			tokens = append(tokens, openingToken{kind: tokenDate, date: date, text: match[0]})
+			line = line[len(match[0]):]
+			continue
+		}
+
+		if match := openingTimePattern.FindStringSubmatch(line); match != nil {
+			hours, _ := strconv.Atoi(match[1])
+			minutes := 0
+			if match[2] != "" {
+				minutes, _ = strconv.Atoi(match[2])
+			}
+			if minutes > 59 || hours*60+minutes > minutesPerDay {
+				return nil, fmt.Errorf("invalid time '%s'", match[0])
+			}
+			tokens = append(tokens, openingToken{kind: tokenTime, minutes: hours*60 + minutes, text: match[0]})
+			line = line[len(match[0]):]
+			continue
+		}
+
+		if match := openingWordPattern.FindString(line); match != "" {
+			line = line[len(match):]
+			word := strings.TrimSuffix(match, ".")
+			if openingIgnoredWords[word] {
+				continue
+			}
+			if token, ok := openingWords[word]; ok {
+				token.text = match
+				tokens = append(tokens, token)
+				continue
+			}
+			return nil, fmt.Errorf("unknown word '%s'", match)
+		}
+
+		switch line[0] {
+		case '-':
+			tokens = append(tokens, openingToken{kind: tokenRange, text: "-"})
+		case ',', ';', '/', '&', '+':
+			tokens = append(tokens, openingToken{kind: tokenSeparator, text: line[:1]})
+		default:
+			if strings.HasPrefix(line, "–") || strings.HasPrefix(line, "—") {
+				tokens = append(tokens, openingToken{kind: tokenRange, text: "-"})
+				line = line[len("–"):]
+				continue
+			}
+			return nil, fmt.Errorf("unexpected character '%s'", string([]rune(line)[0]))
+		}
+		line = line[1:]
+	}
+	return tokens, nil
+}
+
+// parseOpeningHoursLine parses a single line of opening hours.
+// A line consists of one or more groups of days followed by time ranges or "closed".
+// Days may be weekdays, weekday ranges, dates or holidays.
+func parseOpeningHoursLine(line string) ([]OpeningRule, error) {
+	tokens, err := tokenizeOpeningHours(line)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := openingHoursParser{tokens: tokens}
+	rules := make([]OpeningRule, 0)
+	for !parser.done() {
+		selectors, err := parser.parseDays()
+		if err != nil {
+			return nil, err
+		}
+
+		ranges, closed, err := parser.parseTimes()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, selector := range selectors {
+			if closed {
+				selector.Closed = true
+				rules = append(rules, selector)
+				continue
+			}
+			for _, timeRange := range ranges {
+				rule := selector
+				rule.Opens = timeRange[0]
+				rule.Closes = timeRange[1]
+				rules = append(rules, rule)
+			}
+		}
+	}
+	return rules, nil
+}
+
+type openingHoursParser struct {
+	tokens []openingToken
+	pos    int
+}
+
+func (p *openingHoursParser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *openingHoursParser) peek(offset int) (openingToken, bool) {
+	if p.pos+offset >= len(p.tokens) {
+		return openingToken{}, false
+	}
+	return p.tokens[p.pos+offset], true
+}
+
+func (p *openingHoursParser) isKind(offset int, kind openingTokenKind) bool {
+	token, ok := p.peek(offset)
+	return ok && token.kind == kind
+}
+
+func (p *openingHoursParser) isDay(offset int) bool {
+	return p.isKind(offset, tokenWeekday) || p.isKind(offset, tokenDate) || p.isKind(offset, tokenHolidays)
+}
+
+// parseDays parses a list of days, like "Mo-Mi, Fr und Feiertage" and returns the rules without times
+func (p *openingHoursParser) parseDays() ([]OpeningRule, error) {
+	if !p.isDay(0) {
+		return nil, errMissingDays
+	}
+
+	weekdays := make([]time.Weekday, 0)
+	rules := make([]OpeningRule, 0)
+	for {
+		token, _ := p.peek(0)
+		p.pos++
+		switch token.kind {
+		case tokenDate:
+			rules = append(rules, OpeningRule{Date: token.date})
+		case tokenHolidays:
+			rules = append(rules, OpeningRule{Holidays: true})
+		case tokenWeekday:
+			if p.isKind(0, tokenRange) && p.isKind(1, tokenWeekday) {
+				end, _ := p.peek(1)
+				p.pos += 2
+				weekdays = append(weekdays, weekdayRange(token.weekdays[0], end.weekdays[len(end.weekdays)-1])...)
+			} else {
+				weekdays = append(weekdays, token.weekdays...)
+			}
+		}
+
+		if p.isKind(0, tokenSeparator) && p.isDay(1) {
+			p.pos++
+		} else if !p.isDay(0) {
+			break
+		}
+	}
+
+	if len(weekdays) > 0 {
+		rules = append([]OpeningRule{{Weekdays: weekdays}}, rules...)
+	}
+	return rules, nil
+}
+
+// parseTimes parses a list of time ranges, like "8-12 und 14:00-18:00 Uhr" or "geschlossen"
+func (p *openingHoursParser) parseTimes() ([][2]int, bool, error) {
+	if p.isKind(0, tokenClosed) {
+		p.pos++
+		if p.isKind(0, tokenSeparator) && p.isDay(1) {
+			p.pos++
+		}
+		return nil, true, nil
+	}
+
+	ranges := make([][2]int, 0)
+	for p.isKind(0, tokenTime) {
+		if !p.isKind(1, tokenRange) || !p.isKind(2, tokenTime) {
+			return nil, false, errInvalidTime
+		}
+
+		opens, _ := p.peek(0)
+		closes, _ := p.peek(2)
+		p.pos += 3
+		if closes.minutes <= opens.minutes {
+			return nil, false, fmt.Errorf("invalid time range '%s-%s'", opens.text, closes.text)
+		}
+		ranges = append(ranges, [2]int{opens.minutes, closes.minutes})
+
+		if p.isKind(0, tokenSeparator) {
+			p.pos++
+		}
+	}
+
+	if len(ranges) == 0 {
+		return nil, false, errMissingTimes
+	}
+	return ranges, false, nil
+}
+
+// weekdayRange returns all weekdays from start to end (inclusive), wrapping around sunday if needed
+func weekdayRange(start, end time.Weekday) []time.Weekday {
+	result := []time.Weekday{start}
+	for day := start; day != end; {
+		day = (day + 1) % 7
+		result = append(result, day)
+	}
+	return result
+}
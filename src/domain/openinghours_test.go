@@ -0,0 +1,219 @@
+/*
+ *   Corona-Warn-App / cwa-map-backend
+ *
+ *   (C) 2020, T-Systems International GmbH
+ *
+ *   Deutsche Telekom AG and all other contributors /
+ *   copyright owners license this file to you under the Apache
+ *   License, Version 2.0 (the "License"); you may not use this
+ *   file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing,
+ *   software distributed under the License is distributed on an
+ *   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *   KIND, either express or implied.  See the License for the
+ *   specific language governing permissions and limitations
+ *   under the License.
+ */
+
+package domain
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+var (
+	weekdays = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+	everyDay = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday,
+		time.Saturday, time.Sunday}
+)
+
+func TestParseOpeningHours(t *testing.T) {
+	tests := []struct {
+		name     string
+		lines    []string
+		expected OpeningSchedule
+	}{
+		{"weekday range", []string{"Mo-Fr 08:00-18:00"},
+			OpeningSchedule{{Weekdays: weekdays, Opens: 480, Closes: 1080}}},
+		{"weekday list with several ranges", []string{"Mo, Mi, Fr 8-12 und 14-18 Uhr"},
+			OpeningSchedule{
+				{Weekdays: []time.Weekday{time.Monday, time.Wednesday, time.Friday}, Opens: 480, Closes: 720},
+				{Weekdays: []time.Weekday{time.Monday, time.Wednesday, time.Friday}, Opens: 840, Closes: 1080},
+			}},
+		{"several groups", []string{"Mo-Fr 8-12, Sa 9.30-13 Uhr"},
+			OpeningSchedule{
+				{Weekdays: weekdays, Opens: 480, Closes: 720},
+				{Weekdays: []time.Weekday{time.Saturday}, Opens: 570, Closes: 780},
+			}},
+		{"several lines", []string{"Mo-Fr 8-18", "", "So 10-12"},
+			OpeningSchedule{
+				{Weekdays: weekdays, Opens: 480, Closes: 1080},
+				{Weekdays: []time.Weekday{time.Sunday}, Opens: 600, Closes: 720},
+			}},
+		{"weekday range wrapping around sunday", []string{"Fr-Mo 10-14"},
+			OpeningSchedule{{Weekdays: []time.Weekday{time.Friday, time.Saturday, time.Sunday, time.Monday},
+				Opens: 600, Closes: 840}}},
+		{"dashes", []string{"Mo–Fr von 8:00 bis 12:00"},
+			OpeningSchedule{{Weekdays: weekdays, Opens: 480, Closes: 720}}},
+		{"english", []string{"Weekdays from 8 to 12", "Saturday and Sunday closed"},
+			OpeningSchedule{
+				{Weekdays: weekdays, Opens: 480, Closes: 720},
+				{Weekdays: []time.Weekday{time.Saturday, time.Sunday}, Closed: true},
+			}},
+		{"whole day", []string{"täglich 0-24 Uhr"},
+			OpeningSchedule{{Weekdays: everyDay, Opens: 0, Closes: minutesPerDay}}},
+		{"holidays", []string{"Sa, So und Feiertage geschlossen"},
+			OpeningSchedule{
+				{Weekdays: []time.Weekday{time.Saturday, time.Sunday}, Closed: true},
+				{Holidays: true, Closed: true},
+			}},
+		{"date in every year", []string{"24.12. 8-12"},
+			OpeningSchedule{{Date: "12-24", Opens: 480, Closes: 720}}},
+		{"date of a specific year", []string{"31.12.2021 geschlossen"},
+			OpeningSchedule{{Date: "2021-12-31", Closed: true}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			schedule, warnings := ParseOpeningHours(test.lines)
+			assert.Empty(t, warnings)
+			assert.Equal(t, test.expected, schedule)
+		})
+	}
+}
+
+func TestParseOpeningHoursWarnings(t *testing.T) {
+	tests := []struct {
+		line    string
+		warning string
+	}{
+		{"nach Vereinbarung", "invalid opening hours 'nach Vereinbarung': unknown word 'nach'"},
+		{"Mo-Fr", "invalid opening hours 'Mo-Fr': missing times"},
+		{"8-12 Uhr", "invalid opening hours '8-12 Uhr': missing days"},
+		{"Mo 8-", "invalid opening hours 'Mo 8-': invalid time"},
+		{"Mo 25:00-26:00", "invalid opening hours 'Mo 25:00-26:00': invalid time '25:00'"},
+		{"Mo 8:75-12", "invalid opening hours 'Mo 8:75-12': invalid time '8:75'"},
+		{"32.1. 8-12", "invalid opening hours '32.1. 8-12': invalid date '32.1.'"},
+		{"Mo 8-12 *", "invalid opening hours 'Mo 8-12 *': unexpected character '*'"},
+		{"Mo 12-8", "invalid opening hours 'Mo 12-8': invalid time range '12-8'"},
+		// ranges over midnight must be split at midnight, like "Fr 22-24, Sa 0-2"
+		{"Fr 22:00-02:00", "invalid opening hours 'Fr 22:00-02:00': invalid time range '22:00-02:00'"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.line, func(t *testing.T) {
+			// valid lines are kept, if other lines are invalid
+			schedule, warnings := ParseOpeningHours([]string{"Mo 8-12", test.line})
+			assert.Equal(t, []string{test.warning}, warnings)
+			assert.Equal(t, OpeningSchedule{{Weekdays: []time.Weekday{time.Monday}, Opens: 480, Closes: 720}}, schedule)
+		})
+	}
+}
+
+func TestOpeningScheduleIsOpen(t *testing.T) {
+	berlin := func(value string) time.Time {
+		result, err := time.ParseInLocation("2006-01-02 15:04", value, OpeningHoursLocation)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+	utc := func(value string) time.Time {
+		result, err := time.Parse("2006-01-02 15:04", value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	schedule, _ := ParseOpeningHours([]string{
+		"Mo-Fr 08:00-18:00",
+		"Sa 9-13",
+		"So 2-4",
+		"Feiertage geschlossen",
+		"24.12. 8-12",
+		"31.12.2021 geschlossen",
+		"Fr 22-24, Sa 0-2",
+	})
+	withoutHolidays, _ := ParseOpeningHours([]string{"Mo-Fr 08:00-18:00"})
+
+	tests := []struct {
+		name     string
+		schedule OpeningSchedule
+		time     time.Time
+		open     bool
+	}{
+		{"weekday", schedule, berlin("2021-03-15 10:00"), true},
+		{"before opening", schedule, berlin("2021-03-15 07:59"), false},
+		{"opening", schedule, berlin("2021-03-15 08:00"), true},
+		{"before closing", schedule, berlin("2021-03-15 17:59"), true},
+		{"closing", schedule, berlin("2021-03-15 18:00"), false},
+		{"saturday", schedule, berlin("2021-03-20 12:00"), true},
+		{"sunday", schedule, berlin("2021-03-21 12:00"), false},
+		{"empty schedule", nil, berlin("2021-03-15 10:00"), false},
+
+		// ranges over midnight are split into two rules
+		{"before midnight", schedule, berlin("2021-03-19 23:30"), true},
+		{"after midnight", schedule, berlin("2021-03-20 01:30"), true},
+		{"after midnight in utc", schedule, utc("2021-03-20 00:30"), true},
+
+		// the time is evaluated in the time zone of the opening hours
+		{"winter time", withoutHolidays, utc("2021-01-04 06:30"), false},
+		{"winter time opened", withoutHolidays, utc("2021-01-04 07:30"), true},
+		{"summer time", withoutHolidays, utc("2021-07-05 06:30"), true},
+		{"summer time closed", withoutHolidays, utc("2021-07-05 16:30"), false},
+		{"before switching to summer time", schedule, utc("2021-03-28 00:59"), false},
+		{"after switching to summer time", schedule, utc("2021-03-28 01:00"), true},
+		{"before switching to winter time", schedule, utc("2021-10-31 00:30"), true},
+		{"after switching to winter time", schedule, utc("2021-10-31 02:30"), true},
+		{"after closing in winter time", schedule, utc("2021-10-31 03:00"), false},
+
+		// holiday and date rules replace the weekday rules
+		{"good friday", schedule, berlin("2021-04-02 10:00"), false},
+		{"easter monday", schedule, berlin("2021-04-05 10:00"), false},
+		{"whit monday", schedule, berlin("2021-05-24 10:00"), false},
+		{"german unity day", schedule, berlin("2021-10-03 03:00"), false},
+		{"holiday without holiday rules", withoutHolidays, berlin("2021-04-02 10:00"), true},
+		{"date in every year", schedule, berlin("2021-12-24 10:00"), true},
+		{"date in every year after closing", schedule, berlin("2021-12-24 13:00"), false},
+		{"date of a specific year", schedule, berlin("2021-12-31 10:00"), false},
+		{"date of another year", schedule, berlin("2022-12-30 10:00"), true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.open, test.schedule.IsOpen(test.time))
+		})
+	}
+}
+
+func TestIsPublicHoliday(t *testing.T) {
+	tests := []struct {
+		date    string
+		holiday bool
+	}{
+		{"2021-01-01", true},
+		{"2021-04-02", true},
+		{"2021-04-04", false},
+		{"2021-04-05", true},
+		{"2021-05-13", true},
+		{"2021-05-24", true},
+		{"2022-04-15", true},
+		{"2022-04-18", true},
+		{"2021-12-24", false},
+		{"2021-12-26", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.date, func(t *testing.T) {
+			date, _ := time.ParseInLocation("2006-01-02", test.date, OpeningHoursLocation)
+			assert.Equal(t, test.holiday, IsPublicHoliday(date))
+		})
+	}
+}
@@ -34,4 +34,7 @@ type Operator struct {
 	BugReportsReceiver *string
 	Notified           *time.Time
 	NotificationToken  *string
+
+	// ImportProfileUUID is the import profile used for the imports of the operator, if not selected otherwise
+	ImportProfileUUID *string
 }
@@ -0,0 +1,136 @@
+/*
+ *   Corona-Warn-App / cwa-map-backend
+ *
+ *   (C) 2020, T-Systems International GmbH
+ *
+ *   Deutsche Telekom AG and all other contributors /
+ *   copyright owners license this file to you under the Apache
+ *   License, Version 2.0 (the "License"); you may not use this
+ *   file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing,
+ *   software distributed under the License is distributed on an
+ *   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *   KIND, either express or implied.  See the License for the
+ *   specific language governing permissions and limitations
+ *   under the License.
+ */
+
+package geocoding
+
+import (
+	"com.t-systems-mms.cwa/domain"
+	"context"
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sirupsen/logrus"
+	"strings"
+	"time"
+)
+
+var (
+	cacheHitsCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "cwa_map_geocoding_cache_hit_count",
+		Help: "The total count of geocoding requests answered from the cache",
+	})
+
+	cacheMissesCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "cwa_map_geocoding_cache_miss_count",
+		Help: "The total count of geocoding requests not found in the cache",
+	})
+)
+
+// CacheEntry is a cached geocoding result.
+// Err is nil or one of ErrNoResult and ErrTooManyResults, which are cached as well.
+type CacheEntry struct {
+	Result  Result
+	Err     error
+	Created time.Time
+}
+
+// ResultCache stores geocoding results by their normalized address
+type ResultCache interface {
+	// GetResult returns the cached entry for the address and reports if there is an entry
+	GetResult(ctx context.Context, address string) (CacheEntry, bool, error)
+
+	// PutResult stores the entry for the address, replacing existing entries
+	PutResult(ctx context.Context, address string, entry CacheEntry) error
+}
+
+// CachingGeocoder is a Geocoder, which caches the results of another Geocoder
+type CachingGeocoder struct {
+	geocoder    Geocoder
+	cache       ResultCache
+	ttl         time.Duration
+	negativeTTL time.Duration
+}
+
+// NewCachingGeocoder creates a geocoder, which caches the results of the given geocoder for ttl.
+// Missing and ambiguous results are only cached for negativeTTL, as the data of the provider may be
+// completed in the meantime.
+func NewCachingGeocoder(geocoder Geocoder, cache ResultCache, ttl, negativeTTL time.Duration) *CachingGeocoder {
+	return &CachingGeocoder{
+		geocoder:    geocoder,
+		cache:       cache,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+	}
+}
+
+func (g *CachingGeocoder) GetCoordinates(ctx context.Context, address string) (Result, error) {
+	return g.cached(ctx, NormalizeAddress(address), func() (Result, error) {
+		return g.geocoder.GetCoordinates(ctx, address)
+	})
+}
+
+func (g *CachingGeocoder) ReverseGeocode(ctx context.Context, coordinates domain.Coordinates) (Result, error) {
+	return g.cached(ctx, reverseCacheKey(coordinates), func() (Result, error) {
+		return g.geocoder.ReverseGeocode(ctx, coordinates)
+	})
+}
+
+// cached returns the cached result for the key or the result of the given function, which is cached afterwards
+func (g *CachingGeocoder) cached(ctx context.Context, key string, geocode func() (Result, error)) (Result, error) {
+	entry, found, err := g.cache.GetResult(ctx, key)
+	if err != nil {
+		logrus.WithError(err).Warn("Error reading geocoding cache")
+	} else if found && time.Since(entry.Created) < g.getTTL(entry) {
+		cacheHitsCounter.Inc()
+		return entry.Result, entry.Err
+	}
+
+	cacheMissesCounter.Inc()
+	result, err := geocode()
+	if err == nil || err == ErrNoResult || err == ErrTooManyResults {
+		entry := CacheEntry{Result: result, Err: err, Created: time.Now()}
+		if err := g.cache.PutResult(ctx, key, entry); err != nil {
+			logrus.WithError(err).Warn("Error writing geocoding cache")
+		}
+	}
+	return result, err
+}
+
+// getTTL returns the time the entry is valid
+func (g *CachingGeocoder) getTTL(entry CacheEntry) time.Duration {
+	if entry.Err != nil {
+		return g.negativeTTL
+	}
+	return g.ttl
+}
+
+// reverseCacheKey returns the cache key of the coordinates, rounded to about 10 meters
+func reverseCacheKey(coordinates domain.Coordinates) string {
+	return fmt.Sprintf("@%.4f,%.4f", coordinates.Latitude, coordinates.Longitude)
+}
+
+// NormalizeAddress normalizes the address for caching, so addresses only differing in case,
+// whitespace or punctuation share the same cache entry
+func NormalizeAddress(address string) string {
+	address = strings.ToLower(address)
+	address = strings.NewReplacer(",", " ", ";", " ", "\n", " ").Replace(address)
+	return strings.Join(strings.Fields(address), " ")
+}
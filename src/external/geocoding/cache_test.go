@@ -0,0 +1,111 @@
+/*
+ *   Corona-Warn-App / cwa-map-backend
+ *
+ *   (C) 2020, T-Systems International GmbH
+ *
+ *   Deutsche Telekom AG and all other contributors /
+ *   copyright owners license this file to you under the Apache
+ *   License, Version 2.0 (the "License"); you may not use this
+ *   file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing,
+ *   software distributed under the License is distributed on an
+ *   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *   KIND, either express or implied.  See the License for the
+ *   specific language governing permissions and limitations
+ *   under the License.
+ */
+
+package geocoding
+
+import (
+	"com.t-systems-mms.cwa/domain"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// memoryCache is a ResultCache keeping the entries in memory
+type memoryCache map[string]CacheEntry
+
+func (c memoryCache) GetResult(_ context.Context, address string) (CacheEntry, bool, error) {
+	entry, found := c[address]
+	return entry, found, nil
+}
+
+func (c memoryCache) PutResult(_ context.Context, address string, entry CacheEntry) error {
+	c[address] = entry
+	return nil
+}
+
+// countingGeocoder returns the given error and counts the calls
+type countingGeocoder struct {
+	err   error
+	calls int
+}
+
+func (g *countingGeocoder) GetCoordinates(_ context.Context, address string) (Result, error) {
+	g.calls++
+	if g.err != nil {
+		return Result{}, g.err
+	}
+	return Result{Address: address}, nil
+}
+
+func (g *countingGeocoder) ReverseGeocode(_ context.Context, _ domain.Coordinates) (Result, error) {
+	g.calls++
+	return Result{}, g.err
+}
+
+func TestCachingGeocoderTTL(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		age    time.Duration
+		cached bool
+	}{
+		{"result", nil, 2 * time.Hour, true},
+		{"expired result", nil, 11 * 24 * time.Hour, false},
+		{"missing result", ErrNoResult, 30 * time.Minute, true},
+		{"expired missing result", ErrNoResult, 2 * time.Hour, false},
+		{"ambiguous result", ErrTooManyResults, 30 * time.Minute, true},
+		{"expired ambiguous result", ErrTooManyResults, 2 * time.Hour, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cache := memoryCache{}
+			provider := &countingGeocoder{err: test.err}
+			geocoder := NewCachingGeocoder(provider, cache, 10*24*time.Hour, time.Hour)
+
+			_, err := geocoder.GetCoordinates(context.Background(), "Invalidenstr. 1, 10115 Berlin")
+			assert.Equal(t, test.err, err)
+			assert.Equal(t, 1, provider.calls)
+
+			entry := cache["invalidenstr. 1 10115 berlin"]
+			entry.Created = entry.Created.Add(-test.age)
+			cache["invalidenstr. 1 10115 berlin"] = entry
+
+			_, err = geocoder.GetCoordinates(context.Background(), "Invalidenstr. 1, 10115 Berlin")
+			assert.Equal(t, test.err, err)
+			if test.cached {
+				assert.Equal(t, 1, provider.calls)
+			} else {
+				assert.Equal(t, 2, provider.calls)
+			}
+		})
+	}
+}
+
+func TestCachingGeocoderSkipsUnavailableProvider(t *testing.T) {
+	cache := memoryCache{}
+	geocoder := NewCachingGeocoder(&countingGeocoder{err: ErrUnavailable}, cache, time.Hour, time.Hour)
+
+	_, err := geocoder.GetCoordinates(context.Background(), "Invalidenstr. 1, 10115 Berlin")
+	assert.Equal(t, ErrUnavailable, err)
+	assert.Empty(t, cache)
+}
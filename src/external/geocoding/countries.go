@@ -0,0 +1,73 @@
+/*
+ *   Corona-Warn-App / cwa-map-backend
+ *
+ *   (C) 2020, T-Systems International GmbH
+ *
+ *   Deutsche Telekom AG and all other contributors /
+ *   copyright owners license this file to you under the Apache
+ *   License, Version 2.0 (the "License"); you may not use this
+ *   file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing,
+ *   software distributed under the License is distributed on an
+ *   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *   KIND, either express or implied.  See the License for the
+ *   specific language governing permissions and limitations
+ *   under the License.
+ */
+
+package geocoding
+
+import (
+	"com.t-systems-mms.cwa/domain"
+	_ "embed"
+	"encoding/json"
+	"strings"
+)
+
+// countriesData contains a simplified outline per country (ISO 3166-1 alpha-2, lower case)
+// as a list of longitude and latitude pairs. The outlines are slightly larger than the countries,
+// so they are suitable for plausibility checks only.
+//
+//go:embed countries.json
+var countriesData []byte
+
+var countryOutlines = loadCountryOutlines()
+
+func loadCountryOutlines() map[string][][2]float64 {
+	var outlines map[string][][2]float64
+	if err := json.Unmarshal(countriesData, &outlines); err != nil {
+		panic(err)
+	}
+	return outlines
+}
+
+// HasCountryOutline reports if the outline of the given country is known
+func HasCountryOutline(country string) bool {
+	_, found := countryOutlines[strings.ToLower(country)]
+	return found
+}
+
+// CountryContains reports if the coordinates are located within the given country
+// and if the outline of the country is known at all.
+func CountryContains(country string, coordinates domain.Coordinates) (bool, bool) {
+	outline, found := countryOutlines[strings.ToLower(country)]
+	if !found {
+		return false, false
+	}
+
+	// ray casting, counting the edges crossed by a ray from the coordinates to the east
+	inside := false
+	for i, j := 0, len(outline)-1; i < len(outline); j, i = i, i+1 {
+		lngI, latI := outline[i][0], outline[i][1]
+		lngJ, latJ := outline[j][0], outline[j][1]
+		if (latI > coordinates.Latitude) != (latJ > coordinates.Latitude) &&
+			coordinates.Longitude < (lngJ-lngI)*(coordinates.Latitude-latI)/(latJ-latI)+lngI {
+			inside = !inside
+		}
+	}
+	return inside, true
+}
@@ -0,0 +1,108 @@
+/*
+ *   Corona-Warn-App / cwa-map-backend
+ *
+ *   (C) 2020, T-Systems International GmbH
+ *
+ *   Deutsche Telekom AG and all other contributors /
+ *   copyright owners license this file to you under the Apache
+ *   License, Version 2.0 (the "License"); you may not use this
+ *   file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing,
+ *   software distributed under the License is distributed on an
+ *   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *   KIND, either express or implied.  See the License for the
+ *   specific language governing permissions and limitations
+ *   under the License.
+ */
+
+package geocoding
+
+import (
+	"com.t-systems-mms.cwa/domain"
+	"context"
+	"errors"
+	"github.com/sirupsen/logrus"
+	"net"
+	"net/url"
+)
+
+// FallbackGeocoder tries the geocoders in the given order, until one of them returns a result.
+// The next geocoder is only used, if the previous one found no result or is not available.
+type FallbackGeocoder struct {
+	geocoders []Geocoder
+}
+
+func NewFallbackGeocoder(geocoders ...Geocoder) *FallbackGeocoder {
+	return &FallbackGeocoder{geocoders: geocoders}
+}
+
+func (g *FallbackGeocoder) GetCoordinates(ctx context.Context, address string) (Result, error) {
+	err := ErrNoResult
+	for i, geocoder := range g.geocoders {
+		var result Result
+		if result, err = geocoder.GetCoordinates(ctx, address); err == nil {
+			return result, nil
+		} else if !isFallbackError(ctx, err) {
+			return Result{}, err
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"address":  address,
+			"geocoder": i,
+		}).WithError(err).Debug("Geocoding failed, trying next geocoder")
+	}
+	return Result{}, err
+}
+
+// ReverseGeocode uses the first result, which is more precise than LocationApproximate.
+// Approximate results, e.g. of the postal code dataset, are only used if no other geocoder returns a result
+// or if a following geocoder failed.
+func (g *FallbackGeocoder) ReverseGeocode(ctx context.Context, coordinates domain.Coordinates) (Result, error) {
+	var approximate *Result
+	err := ErrNoResult
+	for i, geocoder := range g.geocoders {
+		var result Result
+		if result, err = geocoder.ReverseGeocode(ctx, coordinates); err == nil {
+			if result.LocationType != domain.LocationApproximate {
+				return result, nil
+			}
+			if approximate == nil {
+				approximate = &result
+			}
+			continue
+		} else if !isFallbackError(ctx, err) {
+			break
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"latitude":  coordinates.Latitude,
+			"longitude": coordinates.Longitude,
+			"geocoder":  i,
+		}).WithError(err).Debug("Reverse geocoding failed, trying next geocoder")
+	}
+
+	if approximate != nil {
+		return *approximate, nil
+	}
+	return Result{}, err
+}
+
+// isFallbackError reports if the error allows using the next geocoder.
+// This is the case for missing results and for temporary or network errors of the provider,
+// but not if the request itself has been canceled.
+func isFallbackError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var netErr net.Error
+	var urlErr *url.Error
+	return errors.Is(err, ErrNoResult) ||
+		errors.Is(err, ErrUnavailable) ||
+		errors.As(err, &netErr) ||
+		errors.As(err, &urlErr)
+}
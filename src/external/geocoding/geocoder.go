@@ -33,11 +33,20 @@ type Result struct {
 	Coordinates domain.Coordinates
 	Zip         string
 	Region      string
+
+	// LocationType is the accuracy of the result
+	LocationType domain.GeocodingLocationType
+
+	// Provider is the name of the geocoding provider, which returned the result
+	Provider string
 }
 
 var (
 	ErrNoResult       = errors.New("no results")
 	ErrTooManyResults = errors.New("too many results")
+
+	// ErrUnavailable is returned, if the provider is temporarily not available, e.g. because of a rate limit
+	ErrUnavailable = errors.New("geocoding provider unavailable")
 )
 
 // Geocoder interface provides common functions for different geocoding implementations.
@@ -46,4 +55,7 @@ type Geocoder interface {
 	//
 	// address could be postal codes, cities or complete addresses
 	GetCoordinates(ctx context.Context, address string) (Result, error)
+
+	// ReverseGeocode resolves the given coordinates to the nearest address
+	ReverseGeocode(ctx context.Context, coordinates domain.Coordinates) (Result, error)
 }
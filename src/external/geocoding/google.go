@@ -25,8 +25,10 @@ import (
 	"com.t-systems-mms.cwa/core/util"
 	"com.t-systems-mms.cwa/domain"
 	"context"
+	"fmt"
 	"github.com/sirupsen/logrus"
 	"googlemaps.github.io/maps"
+	"strings"
 )
 
 type GoogleGeocoder struct {
@@ -35,8 +37,20 @@ type GoogleGeocoder struct {
 }
 
 type GoogleGeocoderConfig struct {
-	ApiKey  string
+	ApiKey string
+
+	// Country restricts the results to the given country (ISO 3166-1 alpha-2), if not empty
 	Country string
+
+	// Region biases the results to the given region (ccTLD), if not empty
+	Region string
+
+	// Language of the results, if empty google selects the language
+	Language string
+
+	// ResultTypes are the accepted result types in the order of their preference,
+	// used to select a result if an address has multiple results
+	ResultTypes []string
 }
 
 func NewGoogleGeocoder(config GoogleGeocoderConfig) (*GoogleGeocoder, error) {
@@ -56,16 +70,21 @@ func (g *GoogleGeocoder) GetCoordinates(ctx context.Context, address string) (Re
 		"address": address,
 	}).Debug("GetCoordinates")
 
-	results, err := g.client.Geocode(ctx, &maps.GeocodingRequest{
-		Address: address,
-		Region:  "de", //TODO move to configuration
-		Components: map[maps.Component]string{
-			"country": "de", //TODO move to configuration
-		},
-	})
+	request := &maps.GeocodingRequest{
+		Address:  address,
+		Region:   g.config.Region,
+		Language: g.config.Language,
+	}
+	if g.config.Country != "" {
+		request.Components = map[maps.Component]string{
+			maps.ComponentCountry: g.config.Country,
+		}
+	}
+
+	results, err := g.client.Geocode(ctx, request)
 
 	if err != nil {
-		return Result{}, err
+		return Result{}, g.mapError(err)
 	}
 
 	if len(results) == 0 {
@@ -74,13 +93,7 @@ func (g *GoogleGeocoder) GetCoordinates(ctx context.Context, address string) (Re
 
 	var result *maps.GeocodingResult
 	if len(results) > 1 {
-		for _, r := range results {
-			if r.Types != nil && len(r.Types) > 0 && util.ArrayContainsOne(r.Types, "street_address") {
-				result = &r
-				break
-			}
-		}
-
+		result = g.selectResult(results)
 		if result == nil {
 			return Result{}, ErrTooManyResults
 		}
@@ -88,10 +101,37 @@ func (g *GoogleGeocoder) GetCoordinates(ctx context.Context, address string) (Re
 		result = &results[0]
 	}
 
+	return g.toResult(result), nil
+}
+
+func (g *GoogleGeocoder) ReverseGeocode(ctx context.Context, coordinates domain.Coordinates) (Result, error) {
+	logrus.WithFields(logrus.Fields{
+		"latitude":  coordinates.Latitude,
+		"longitude": coordinates.Longitude,
+	}).Debug("ReverseGeocode")
+
+	results, err := g.client.ReverseGeocode(ctx, &maps.GeocodingRequest{
+		LatLng:   &maps.LatLng{Lat: coordinates.Latitude, Lng: coordinates.Longitude},
+		Language: g.config.Language,
+	})
+	if err != nil {
+		return Result{}, g.mapError(err)
+	}
+
+	// the results are ordered from the most precise to the least precise address
+	if len(results) == 0 {
+		return Result{}, ErrNoResult
+	}
+	return g.toResult(&results[0]), nil
+}
+
+func (g *GoogleGeocoder) toResult(result *maps.GeocodingResult) Result {
 	return Result{
-		Address: result.FormattedAddress,
-		Region:  g.getAddressComponent(result, "administrative_area_level_1"),
-		Zip:     g.getAddressComponent(result, "postal_code"),
+		Address:      result.FormattedAddress,
+		Region:       g.getAddressComponent(result, "administrative_area_level_1"),
+		Zip:          g.getAddressComponent(result, "postal_code"),
+		LocationType: g.mapLocationType(result.Geometry.LocationType),
+		Provider:     "google",
 		Bounds: domain.Bounds{
 			NorthEast: domain.Coordinates{
 				Longitude: result.Geometry.Viewport.NorthEast.Lng,
@@ -106,7 +146,38 @@ func (g *GoogleGeocoder) GetCoordinates(ctx context.Context, address string) (Re
 			Longitude: result.Geometry.Location.Lng,
 			Latitude:  result.Geometry.Location.Lat,
 		},
-	}, nil
+	}
+}
+
+// selectResult selects the first result with the most preferred of the accepted result types
+func (g *GoogleGeocoder) selectResult(results []maps.GeocodingResult) *maps.GeocodingResult {
+	for _, resultType := range g.config.ResultTypes {
+		for i := range results {
+			if util.ArrayContainsOne(results[i].Types, resultType) {
+				return &results[i]
+			}
+		}
+	}
+	return nil
+}
+
+// mapLocationType maps the location type of google to the common location types
+func (g *GoogleGeocoder) mapLocationType(locationType string) domain.GeocodingLocationType {
+	switch locationType {
+	case "ROOFTOP":
+		return domain.LocationRooftop
+	case "RANGE_INTERPOLATED":
+		return domain.LocationInterpolated
+	}
+	return domain.LocationApproximate
+}
+
+// mapError maps the status of temporary errors to ErrUnavailable
+func (g *GoogleGeocoder) mapError(err error) error {
+	if strings.Contains(err.Error(), "OVER_QUERY_LIMIT") || strings.Contains(err.Error(), "UNKNOWN_ERROR") {
+		return fmt.Errorf("%w: %s", ErrUnavailable, err.Error())
+	}
+	return err
 }
 
 func (g *GoogleGeocoder) getAddressComponent(result *maps.GeocodingResult, component string) string {
@@ -0,0 +1,201 @@
+/*
+ *   Corona-Warn-App / cwa-map-backend
+ *
+ *   (C) 2020, T-Systems International GmbH
+ *
+ *   Deutsche Telekom AG and all other contributors /
+ *   copyright owners license this file to you under the Apache
+ *   License, Version 2.0 (the "License"); you may not use this
+ *   file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing,
+ *   software distributed under the License is distributed on an
+ *   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *   KIND, either express or implied.  See the License for the
+ *   specific language governing permissions and limitations
+ *   under the License.
+ */
+
+package geocoding
+
+import (
+	"com.t-systems-mms.cwa/domain"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const nominatimTimeout = 10 * time.Second
+
+// NominatimGeocoderConfig contains the configuration of a Nominatim compatible geocoding service
+type NominatimGeocoderConfig struct {
+	// BaseURL is the url of the service, like https://nominatim.openstreetmap.org
+	BaseURL string
+
+	// UserAgent identifies the application, as required by the usage policy of the public service
+	UserAgent string
+
+	// Email is sent with each request, so the operator of the service can contact us
+	Email string
+
+	// CountryCodes is a comma separated list of countries the results are limited to
+	CountryCodes string
+}
+
+// NominatimGeocoder resolves addresses using the search API of Nominatim (OpenStreetMap)
+type NominatimGeocoder struct {
+	config NominatimGeocoderConfig
+	client *http.Client
+}
+
+type nominatimResult struct {
+	Lat         string            `json:"lat"`
+	Lon         string            `json:"lon"`
+	DisplayName string            `json:"display_name"`
+	BoundingBox []string          `json:"boundingbox"`
+	Address     map[string]string `json:"address"`
+
+	// Error is set by the reverse API, if there is no result
+	Error string `json:"error"`
+}
+
+func NewNominatimGeocoder(config NominatimGeocoderConfig) *NominatimGeocoder {
+	return &NominatimGeocoder{
+		config: config,
+		client: &http.Client{Timeout: nominatimTimeout},
+	}
+}
+
+func (g *NominatimGeocoder) GetCoordinates(ctx context.Context, address string) (Result, error) {
+	logrus.WithFields(logrus.Fields{
+		"address": address,
+	}).Debug("GetCoordinates")
+
+	query := url.Values{}
+	query.Set("q", address)
+	query.Set("format", "jsonv2")
+	query.Set("addressdetails", "1")
+	query.Set("limit", "1")
+	if g.config.CountryCodes != "" {
+		query.Set("countrycodes", g.config.CountryCodes)
+	}
+	if g.config.Email != "" {
+		query.Set("email", g.config.Email)
+	}
+
+	var results []nominatimResult
+	if err := g.get(ctx, "/search", query, &results); err != nil {
+		return Result{}, err
+	}
+
+	if len(results) == 0 {
+		return Result{}, ErrNoResult
+	}
+	return results[0].toResult()
+}
+
+func (g *NominatimGeocoder) ReverseGeocode(ctx context.Context, coordinates domain.Coordinates) (Result, error) {
+	logrus.WithFields(logrus.Fields{
+		"latitude":  coordinates.Latitude,
+		"longitude": coordinates.Longitude,
+	}).Debug("ReverseGeocode")
+
+	query := url.Values{}
+	query.Set("lat", strconv.FormatFloat(coordinates.Latitude, 'f', -1, 64))
+	query.Set("lon", strconv.FormatFloat(coordinates.Longitude, 'f', -1, 64))
+	query.Set("format", "jsonv2")
+	query.Set("addressdetails", "1")
+	if g.config.Email != "" {
+		query.Set("email", g.config.Email)
+	}
+
+	var result nominatimResult
+	if err := g.get(ctx, "/reverse", query, &result); err != nil {
+		return Result{}, err
+	}
+
+	if result.Error != "" {
+		return Result{}, ErrNoResult
+	}
+	return result.toResult()
+}
+
+// get calls the given endpoint and parses the json response into target
+func (g *NominatimGeocoder) get(ctx context.Context, path string, query url.Values, target interface{}) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		strings.TrimSuffix(g.config.BaseURL, "/")+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Accept", "application/json")
+	if g.config.UserAgent != "" {
+		request.Header.Set("User-Agent", g.config.UserAgent)
+	}
+
+	response, err := g.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusTooManyRequests || response.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("%w: nominatim returned status %d", ErrUnavailable, response.StatusCode)
+	} else if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("nominatim returned status %d", response.StatusCode)
+	}
+
+	return json.NewDecoder(response.Body).Decode(target)
+}
+
+func (r *nominatimResult) toResult() (Result, error) {
+	latitude, err := strconv.ParseFloat(r.Lat, 64)
+	if err != nil {
+		return Result{}, err
+	}
+	longitude, err := strconv.ParseFloat(r.Lon, 64)
+	if err != nil {
+		return Result{}, err
+	}
+
+	result := Result{
+		Address:      r.DisplayName,
+		Coordinates:  domain.Coordinates{Latitude: latitude, Longitude: longitude},
+		Zip:          r.Address["postcode"],
+		Region:       r.Address["state"],
+		LocationType: domain.LocationApproximate,
+		Provider:     "nominatim",
+		Bounds: domain.Bounds{
+			NorthEast: domain.Coordinates{Latitude: latitude, Longitude: longitude},
+			SouthWest: domain.Coordinates{Latitude: latitude, Longitude: longitude},
+		},
+	}
+
+	// results with a house number are located at the building
+	if r.Address["house_number"] != "" {
+		result.LocationType = domain.LocationRooftop
+	}
+
+	// the bounding box is given as [south, north, west, east]
+	if len(r.BoundingBox) == 4 {
+		values := make([]float64, 4)
+		for i, value := range r.BoundingBox {
+			if values[i], err = strconv.ParseFloat(value, 64); err != nil {
+				return Result{}, err
+			}
+		}
+		result.Bounds = domain.Bounds{
+			NorthEast: domain.Coordinates{Latitude: values[1], Longitude: values[3]},
+			SouthWest: domain.Coordinates{Latitude: values[0], Longitude: values[2]},
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,247 @@
+/*
+ *   Corona-Warn-App / cwa-map-backend
+ *
+ *   (C) 2020, T-Systems International GmbH
+ *
+ *   Deutsche Telekom AG and all other contributors /
+ *   copyright owners license this file to you under the Apache
+ *   License, Version 2.0 (the "License"); you may not use this
+ *   file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing,
+ *   software distributed under the License is distributed on an
+ *   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *   KIND, either express or implied.  See the License for the
+ *   specific language governing permissions and limitations
+ *   under the License.
+ */
+
+package geocoding
+
+import (
+	"com.t-systems-mms.cwa/domain"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// postalCodeProvider is the provider name of results from the postal code dataset
+const postalCodeProvider = "postalcodes"
+
+// postalCodeMaxDistance is the maximum distance (in km) of coordinates to the nearest postal code for reverse geocoding
+const postalCodeMaxDistance = 20.0
+
+// postalCodeAddressPattern matches a postal code, optionally followed by the city
+var postalCodeAddressPattern = regexp.MustCompile(`^(\d{5})(?:\s+(\D.*))?$`)
+
+// PostalCodeEntry is an entry of the postal code dataset
+type PostalCodeEntry struct {
+	Zip         string
+	City        string
+	Region      string
+	Coordinates domain.Coordinates
+	Bounds      domain.Bounds
+}
+
+// PostalCodeGeocoder resolves postal codes and city names from a locally loaded dataset.
+// Only bare postal codes, postal codes followed by their city and bare city names are resolved, any other address
+// (e.g. complete addresses) is left to the next geocoder of a FallbackGeocoder.
+// The results are centroids of the postal codes, so it must not be used for geocoding centers.
+type PostalCodeGeocoder struct {
+	zips   map[string]PostalCodeEntry
+	cities map[string][]PostalCodeEntry
+}
+
+// LoadPostalCodeGeocoder loads the dataset from the given file, see NewPostalCodeGeocoder
+func LoadPostalCodeGeocoder(path string) (*PostalCodeGeocoder, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return NewPostalCodeGeocoder(file)
+}
+
+// NewPostalCodeGeocoder reads the dataset from the given csv data.
+// The data must be separated by semicolons and start with a header row, followed by rows with the columns
+// zip, city, region, latitude, longitude and optionally the bounds as south, west, north and east.
+func NewPostalCodeGeocoder(reader io.Reader) (*PostalCodeGeocoder, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.Comma = ';'
+	csvReader.FieldsPerRecord = -1
+	csvReader.Comment = '#'
+
+	records, err := csvReader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	geocoder := &PostalCodeGeocoder{
+		zips:   make(map[string]PostalCodeEntry),
+		cities: make(map[string][]PostalCodeEntry),
+	}
+	for i, record := range records {
+		if i == 0 {
+			// header
+			continue
+		}
+
+		entry, err := parsePostalCodeEntry(record)
+		if err != nil {
+			return nil, fmt.Errorf("invalid postal code entry in line %d: %w", i+1, err)
+		}
+		geocoder.zips[entry.Zip] = entry
+		city := normalizeCity(entry.City)
+		geocoder.cities[city] = append(geocoder.cities[city], entry)
+	}
+	return geocoder, nil
+}
+
+func parsePostalCodeEntry(record []string) (PostalCodeEntry, error) {
+	if len(record) != 5 && len(record) != 9 {
+		return PostalCodeEntry{}, fmt.Errorf("expected 5 or 9 columns, got %d", len(record))
+	}
+
+	values := make([]float64, len(record)-3)
+	for i := range values {
+		value, err := strconv.ParseFloat(strings.TrimSpace(record[i+3]), 64)
+		if err != nil {
+			return PostalCodeEntry{}, err
+		}
+		values[i] = value
+	}
+
+	entry := PostalCodeEntry{
+		Zip:         strings.TrimSpace(record[0]),
+		City:        strings.TrimSpace(record[1]),
+		Region:      strings.TrimSpace(record[2]),
+		Coordinates: domain.Coordinates{Latitude: values[0], Longitude: values[1]},
+	}
+
+	if len(values) == 6 {
+		entry.Bounds = domain.Bounds{
+			SouthWest: domain.Coordinates{Latitude: values[2], Longitude: values[3]},
+			NorthEast: domain.Coordinates{Latitude: values[4], Longitude: values[5]},
+		}
+	} else {
+		entry.Bounds = domain.Bounds{SouthWest: entry.Coordinates, NorthEast: entry.Coordinates}
+	}
+	return entry, nil
+}
+
+// Len returns the count of known postal codes
+func (g *PostalCodeGeocoder) Len() int {
+	return len(g.zips)
+}
+
+// GetCoordinates resolves postal codes, which are optionally followed by the city of the postal code,
+// and city names. For cities with several postal codes the result covers all of them.
+// ErrNoResult is returned for any other address.
+func (g *PostalCodeGeocoder) GetCoordinates(_ context.Context, address string) (Result, error) {
+	address = strings.TrimSpace(address)
+	match := postalCodeAddressPattern.FindStringSubmatch(address)
+	if match == nil {
+		entries, found := g.cities[normalizeCity(address)]
+		if !found {
+			return Result{}, ErrNoResult
+		}
+		return mergePostalCodeEntries(entries), nil
+	}
+
+	entry, found := g.zips[match[1]]
+	if !found || (match[2] != "" && normalizeCity(match[2]) != normalizeCity(entry.City)) {
+		return Result{}, ErrNoResult
+	}
+	return Result{
+		Address:      entry.Zip + " " + entry.City,
+		Bounds:       entry.Bounds,
+		Coordinates:  entry.Coordinates,
+		Zip:          entry.Zip,
+		Region:       entry.Region,
+		LocationType: domain.LocationApproximate,
+		Provider:     postalCodeProvider,
+	}, nil
+}
+
+// ReverseGeocode resolves the coordinates to the postal code with the nearest centroid.
+// Coordinates within the bounds of a postal code are resolved to this postal code, if the bounds are known.
+func (g *PostalCodeGeocoder) ReverseGeocode(_ context.Context, coordinates domain.Coordinates) (Result, error) {
+	var nearest *PostalCodeEntry
+	nearestDistance := postalCodeMaxDistance
+	for zip := range g.zips {
+		entry := g.zips[zip]
+		distance := entry.Coordinates.DistanceTo(coordinates)
+		if entry.contains(coordinates) {
+			// prefer entries containing the coordinates over all entries only based on the distance
+			distance -= postalCodeMaxDistance
+		}
+
+		if distance < nearestDistance ||
+			(distance == nearestDistance && nearest != nil && entry.Zip < nearest.Zip) {
+			nearest = &entry
+			nearestDistance = distance
+		}
+	}
+
+	if nearest == nil {
+		return Result{}, ErrNoResult
+	}
+	return Result{
+		Address:      nearest.Zip + " " + nearest.City,
+		Bounds:       nearest.Bounds,
+		Coordinates:  nearest.Coordinates,
+		Zip:          nearest.Zip,
+		Region:       nearest.Region,
+		LocationType: domain.LocationApproximate,
+		Provider:     postalCodeProvider,
+	}, nil
+}
+
+// contains reports if the coordinates are within the bounds of the entry
+func (e PostalCodeEntry) contains(coordinates domain.Coordinates) bool {
+	return coordinates.Latitude >= e.Bounds.SouthWest.Latitude && coordinates.Latitude <= e.Bounds.NorthEast.Latitude &&
+		coordinates.Longitude >= e.Bounds.SouthWest.Longitude && coordinates.Longitude <= e.Bounds.NorthEast.Longitude
+}
+
+// mergePostalCodeEntries combines the entries of a city to a single result
+func mergePostalCodeEntries(entries []PostalCodeEntry) Result {
+	result := Result{
+		Address:      entries[0].City,
+		Region:       entries[0].Region,
+		LocationType: domain.LocationApproximate,
+		Provider:     postalCodeProvider,
+		Bounds: domain.Bounds{
+			NorthEast: domain.Coordinates{Latitude: -90, Longitude: -180},
+			SouthWest: domain.Coordinates{Latitude: 90, Longitude: 180},
+		},
+	}
+	if len(entries) == 1 {
+		result.Zip = entries[0].Zip
+	}
+
+	for _, entry := range entries {
+		result.Coordinates.Latitude += entry.Coordinates.Latitude / float64(len(entries))
+		result.Coordinates.Longitude += entry.Coordinates.Longitude / float64(len(entries))
+		result.Bounds.SouthWest.Latitude = math.Min(result.Bounds.SouthWest.Latitude, entry.Bounds.SouthWest.Latitude)
+		result.Bounds.SouthWest.Longitude = math.Min(result.Bounds.SouthWest.Longitude, entry.Bounds.SouthWest.Longitude)
+		result.Bounds.NorthEast.Latitude = math.Max(result.Bounds.NorthEast.Latitude, entry.Bounds.NorthEast.Latitude)
+		result.Bounds.NorthEast.Longitude = math.Max(result.Bounds.NorthEast.Longitude, entry.Bounds.NorthEast.Longitude)
+		if entry.Region != result.Region {
+			result.Region = ""
+		}
+	}
+	return result
+}
+
+func normalizeCity(city string) string {
+	return strings.Join(strings.Fields(strings.ToLower(city)), " ")
+}
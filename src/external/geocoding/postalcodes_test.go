@@ -0,0 +1,159 @@
+/*
+ *   Corona-Warn-App / cwa-map-backend
+ *
+ *   (C) 2020, T-Systems International GmbH
+ *
+ *   Deutsche Telekom AG and all other contributors /
+ *   copyright owners license this file to you under the Apache
+ *   License, Version 2.0 (the "License"); you may not use this
+ *   file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing,
+ *   software distributed under the License is distributed on an
+ *   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *   KIND, either express or implied.  See the License for the
+ *   specific language governing permissions and limitations
+ *   under the License.
+ */
+
+package geocoding
+
+import (
+	"com.t-systems-mms.cwa/domain"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+const postalCodesTestData = `zip;city;region;latitude;longitude;south;west;north;east
+# comments are ignored
+10115;Berlin;Berlin;52.5323;13.3846;52.5240;13.3660;52.5410;13.4040
+10117;Berlin;Berlin;52.5170;13.3889;52.5060;13.3740;52.5230;13.4120
+80331; München ;Bayern;48.1371;11.5754
+`
+
+func newTestPostalCodeGeocoder(t *testing.T) *PostalCodeGeocoder {
+	geocoder, err := NewPostalCodeGeocoder(strings.NewReader(postalCodesTestData))
+	require.NoError(t, err)
+	return geocoder
+}
+
+func TestNewPostalCodeGeocoder(t *testing.T) {
+	geocoder := newTestPostalCodeGeocoder(t)
+	assert.Equal(t, 3, geocoder.Len())
+
+	// the bounds are optional
+	entry := geocoder.zips["80331"]
+	assert.Equal(t, "München", entry.City)
+	assert.Equal(t, domain.Coordinates{Latitude: 48.1371, Longitude: 11.5754}, entry.Coordinates)
+	assert.Equal(t, domain.Bounds{SouthWest: entry.Coordinates, NorthEast: entry.Coordinates}, entry.Bounds)
+
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"missing columns", "header\n10115;Berlin;Berlin;52.5323\n"},
+		{"invalid coordinates", "header\n10115;Berlin;Berlin;north;13.3846\n"},
+		{"invalid bounds", "header\n10115;Berlin;Berlin;52.5323;13.3846;52.5240;13.3660;52.5410;east\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewPostalCodeGeocoder(strings.NewReader(test.data))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "line 2")
+		})
+	}
+}
+
+func TestLoadPostalCodeGeocoder(t *testing.T) {
+	// the bundled dataset used by default
+	geocoder, err := LoadPostalCodeGeocoder("../../../resources/geocoding/postalcodes.csv")
+	require.NoError(t, err)
+	assert.Greater(t, geocoder.Len(), 0)
+
+	_, err = LoadPostalCodeGeocoder("testdata/missing.csv")
+	assert.Error(t, err)
+}
+
+func TestPostalCodeGeocoderGetCoordinates(t *testing.T) {
+	geocoder := newTestPostalCodeGeocoder(t)
+	tests := []struct {
+		name    string
+		address string
+		// expected is the address of the result, empty if no result is expected
+		expected string
+		zip      string
+	}{
+		{"postal code", "10115", "10115 Berlin", "10115"},
+		{"postal code with spaces", " 10117 ", "10117 Berlin", "10117"},
+		{"postal code and city", "80331  münchen", "80331 München", "80331"},
+		{"postal code and other city", "80331 Berlin", "", ""},
+		{"unknown postal code", "12345", "", ""},
+		{"city", "berlin", "Berlin", ""},
+		{"city with single postal code", "München", "München", "80331"},
+		{"unknown city", "Hamburg", "", ""},
+		{"complete address", "Invalidenstr. 1, 10115 Berlin", "", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := geocoder.GetCoordinates(context.Background(), test.address)
+			if test.expected == "" {
+				assert.True(t, errors.Is(err, ErrNoResult))
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, result.Address)
+			assert.Equal(t, test.zip, result.Zip)
+			assert.Equal(t, domain.LocationApproximate, result.LocationType)
+			assert.Equal(t, postalCodeProvider, result.Provider)
+		})
+	}
+
+	// the result of a city covers all of its postal codes
+	result, err := geocoder.GetCoordinates(context.Background(), "Berlin")
+	require.NoError(t, err)
+	assert.Equal(t, "Berlin", result.Region)
+	assert.InDelta(t, 52.52465, result.Coordinates.Latitude, 1e-9)
+	assert.InDelta(t, 13.38675, result.Coordinates.Longitude, 1e-9)
+	assert.Equal(t, domain.Bounds{
+		SouthWest: domain.Coordinates{Latitude: 52.5060, Longitude: 13.3660},
+		NorthEast: domain.Coordinates{Latitude: 52.5410, Longitude: 13.4120},
+	}, result.Bounds)
+}
+
+func TestPostalCodeGeocoderReverseGeocode(t *testing.T) {
+	geocoder := newTestPostalCodeGeocoder(t)
+	tests := []struct {
+		name        string
+		coordinates domain.Coordinates
+		// expected is the postal code of the result, empty if no result is expected
+		expected string
+	}{
+		{"centroid", domain.Coordinates{Latitude: 48.1371, Longitude: 11.5754}, "80331"},
+		{"near centroid", domain.Coordinates{Latitude: 48.2, Longitude: 11.6}, "80331"},
+		// nearer to the centroid of 10117, but within the bounds of 10115
+		{"within bounds", domain.Coordinates{Latitude: 52.5245, Longitude: 13.3890}, "10115"},
+		{"too far", domain.Coordinates{Latitude: 53.5503, Longitude: 10.0006}, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := geocoder.ReverseGeocode(context.Background(), test.coordinates)
+			if test.expected == "" {
+				assert.True(t, errors.Is(err, ErrNoResult))
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, result.Zip)
+		})
+	}
+}
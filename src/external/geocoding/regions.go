@@ -21,16 +21,59 @@
 
 package geocoding
 
-var regionTranslations = map[string]string{
-	"Rhineland-Palatinate":   "Rheinland-Pfalz",
-	"Bavaria":                "Bayern",
-	"North Rhine-Westphalia": "Nordrhein-Westfalen",
-	"Lower Saxony":           "Niedersachsen",
-	"Saxony":                 "Sachsen",
-	"Saxony-Anhalt":          "Sachsen-Anhalt",
+import (
+	_ "embed"
+	"encoding/json"
+	"strings"
+)
+
+// DefaultCountry is the country used for region translations, if no other country has been set
+const DefaultCountry = "de"
+
+// regionsData contains the translations of region names per country (ISO 3166-1 alpha-2, lower case),
+// mapping the names returned by geocoding providers to the local names.
+//
+//go:embed regions.json
+var regionsData []byte
+
+var (
+	countryRegionTranslations = loadRegionTranslations()
+	regionTranslations        = countryRegionTranslations[DefaultCountry]
+)
+
+func loadRegionTranslations() map[string]map[string]string {
+	var translations map[string]map[string]string
+	if err := json.Unmarshal(regionsData, &translations); err != nil {
+		panic(err)
+	}
+	return translations
+}
+
+// SetRegionCountry selects the country whose region translations are used.
+// It should be called once during startup.
+func SetRegionCountry(country string) {
+	regionTranslations = countryRegionTranslations[strings.ToLower(country)]
+}
+
+// GetRegionAliases returns all known names of the given region, the local name and its translations
+func GetRegionAliases(region string) []string {
+	translation := region
+	for name, local := range regionTranslations {
+		if strings.EqualFold(name, region) {
+			translation = local
+		}
+	}
+
+	aliases := []string{translation}
+	for name, local := range regionTranslations {
+		if strings.EqualFold(local, translation) {
+			aliases = append(aliases, name)
+		}
+	}
+	return aliases
 }
 
-// GetRegionTranslation returns the german translation for some regions.
+// GetRegionTranslation returns the local name for some regions.
 // This is because google gives english names in some cases.
 func GetRegionTranslation(region *string) string {
 	if region == nil {
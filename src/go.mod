@@ -17,6 +17,9 @@ require (
 	github.com/stretchr/testify v1.7.0
 	github.com/vincent-petithory/dataurl v0.0.0-20191104211930-d1553a71de50
 	github.com/xhit/go-simple-mail/v2 v2.10.0
+	golang.org/x/text v0.3.6
+	golang.org/x/time v0.0.0-20200416051211-89c76fbcd5d1
+	google.golang.org/protobuf v1.26.0-rc.1
 	googlemaps.github.io/maps v1.3.2
 	gorm.io/driver/postgres v1.1.0
 	gorm.io/gorm v1.21.10
@@ -73,9 +76,6 @@ require (
 	golang.org/x/net v0.0.0-20210226172049-e18ecbb05110 // indirect
 	golang.org/x/sync v0.0.0-20201207232520-09787c993a3a // indirect
 	golang.org/x/sys v0.0.0-20210603081109-ebe580a85c40 // indirect
-	golang.org/x/text v0.3.6 // indirect
-	golang.org/x/time v0.0.0-20200416051211-89c76fbcd5d1 // indirect
-	google.golang.org/protobuf v1.26.0-rc.1 // indirect
 	gopkg.in/DATA-DOG/go-sqlmock.v1 v1.3.0 // indirect
 	gopkg.in/doug-martin/goqu.v5 v5.0.0 // indirect
 	gopkg.in/go-playground/assert.v1 v1.2.1 // indirect
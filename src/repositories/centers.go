@@ -30,16 +30,42 @@ import (
 	"github.com/doug-martin/goqu"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"math"
+	"strings"
 	"time"
 )
 
+// DistanceUnit is the distance in km of one degree latitude
 const DistanceUnit = 111.045
 
+// TestKindMatch defines how centers are matched against multiple test kinds
+type TestKindMatch string
+
+const (
+	// TestKindMatchAll matches centers offering all of the test kinds
+	TestKindMatchAll TestKindMatch = "all"
+
+	// TestKindMatchAny matches centers offering at least one of the test kinds
+	TestKindMatchAny TestKindMatch = "any"
+)
+
 type SearchParameters struct {
-	Appointment     *domain.AppointmentType
-	TestKind        *domain.TestKind
+	Appointments    []domain.AppointmentType
+	TestKinds       []domain.TestKind
+	TestKindMatch   TestKindMatch
 	DCC             *bool
 	IncludeOutdated *bool
+
+	// Operators restricts the result to centers of the operators with the given uuids
+	Operators []string
+
+	// Regions restricts the result to centers within the given regions, ignoring the case.
+	// All names of a region have to be given, see geocoding.GetRegionAliases.
+	Regions []string
+
+	// OpenAt restricts the result to centers which are open at the given time
+	OpenAt *time.Time
 }
 
 type PagedCentersResult struct {
@@ -47,6 +73,21 @@ type PagedCentersResult struct {
 	Result []domain.Center
 }
 
+// CenterCluster contains the aggregated data of all centers within a cluster cell
+type CenterCluster struct {
+	Count            int
+	Latitude         float64
+	Longitude        float64
+	MinLatitude      float64
+	MaxLatitude      float64
+	MinLongitude     float64
+	MaxLongitude     float64
+	AntigenCount     int
+	PcrCount         int
+	VaccinationCount int
+	AntibodyCount    int
+}
+
 type CenterStatistics struct {
 	TotalCount     int
 	DccCount       int
@@ -60,14 +101,33 @@ type Centers interface {
 
 	FindByBounds(ctx context.Context, target domain.Bounds, params SearchParameters, limit uint) ([]domain.Center, error)
 
+	// FindClusters aggregates the centers within the given bounds to clusters suitable for the given zoom level
+	FindClusters(ctx context.Context, target domain.Bounds, params SearchParameters, zoom uint) ([]CenterCluster, error)
+
+	// FindNearby finds the centers within radius (in km) around the given location, ordered by their distance
+	FindNearby(ctx context.Context, location domain.Coordinates, radius float64, params SearchParameters, limit uint) ([]domain.CenterWithDistance, error)
+
 	// FindByOperatorAndUserReference find the center for the given operator und user reference
 	FindByOperatorAndUserReference(ctx context.Context, operator, number string) (domain.Center, error)
 
 	FindByOperator(ctx context.Context, operator string, search string, page PageRequest) (PagedCentersResult, error)
 
+	// FindAllByOperator finds all centers of the operator, ordered by their user reference
+	FindAllByOperator(ctx context.Context, operator string) ([]domain.Center, error)
+
+	// FindGeocodingIssues finds the centers of the operator, whose geocoding failed, is ambiguous or only approximate
+	FindGeocodingIssues(ctx context.Context, operator string, page PageRequest) (PagedCentersResult, error)
+
+	// Search finds the centers matching the given full text query, ordered by their relevance
+	Search(ctx context.Context, query string, params SearchParameters, page PageRequest) (PagedCentersResult, error)
+
 	// Save persists the given center
 	Save(ctx context.Context, center *domain.Center) error
 
+	// Restore inserts the center with its uuid or replaces all columns of the existing center with this uuid,
+	// using a single statement. The associations of the center are not saved.
+	Restore(ctx context.Context, center *domain.Center) error
+
 	// SaveMultiple saves the given centers
 	SaveMultiple(ctx context.Context, center []domain.Center) ([]domain.Center, error)
 
@@ -100,7 +160,7 @@ func (r *centersRepository) FindAll() ([]domain.Center, error) {
 }
 
 func (r *centersRepository) Delete(ctx context.Context, center domain.Center) error {
-	return r.db.Delete(&center).Error
+	return r.GetTX(ctx).Delete(&center).Error
 }
 
 func (r *centersRepository) FindByUUID(ctx context.Context, uuid string) (domain.Center, error) {
@@ -133,6 +193,13 @@ func (r *centersRepository) Save(ctx context.Context, center *domain.Center) err
 	return r.GetTX(ctx).Save(center).Error
 }
 
+func (r *centersRepository) Restore(ctx context.Context, center *domain.Center) error {
+	return r.GetTX(ctx).
+		Omit(clause.Associations).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "uuid"}}, UpdateAll: true}).
+		Create(center).Error
+}
+
 func (r *centersRepository) SaveMultiple(ctx context.Context, centers []domain.Center) ([]domain.Center, error) {
 	result := make([]domain.Center, len(centers))
 	err := r.db.Transaction(func(tx *gorm.DB) error {
@@ -173,23 +240,230 @@ func (r *centersRepository) FindByOperator(ctx context.Context, operator string,
 	return result, err
 }
 
+func (r *centersRepository) FindAllByOperator(ctx context.Context, operator string) ([]domain.Center, error) {
+	var centers []domain.Center
+	err := r.GetTX(ctx).
+		Where("operator_uuid = ?", operator).
+		Order("user_reference, uuid").
+		Find(&centers).Error
+	return centers, err
+}
+
+func (r *centersRepository) FindGeocodingIssues(ctx context.Context, operator string, page PageRequest) (PagedCentersResult, error) {
+	baseQuery := r.GetTX(ctx).Model(&domain.Center{}).
+		Where("operator_uuid = ?", operator).
+		Where("geocoding_status in ? or (geocoding_status = ? and geocoding_location_type = ?) or "+
+			"(latitude = 0 and longitude = 0 and geocoding_status is distinct from ?)",
+			[]domain.GeocodingStatus{domain.GeocodingAmbiguous, domain.GeocodingFailed},
+			domain.GeocodingOk, domain.LocationApproximate, domain.GeocodingPending).
+		Order("user_reference")
+
+	result := PagedCentersResult{}
+	if err := baseQuery.Count(&result.Count).Error; err != nil {
+		return result, err
+	}
+
+	err := baseQuery.
+		Offset(page.Page * page.Size).
+		Limit(page.Size).
+		Find(&result.Result).
+		Error
+
+	return result, err
+}
+
+// Search finds the centers matching the given full text query, ordered by their relevance.
+// The query is matched against name, operator name, address, zip and region of the centers
+// using german stemming and ignoring accents.
+func (r *centersRepository) Search(ctx context.Context, query string, params SearchParameters, page PageRequest) (PagedCentersResult, error) {
+	tsQuery := "websearch_to_tsquery('german_unaccent', ?)"
+	builder := r.buildSearchQuery(params).
+		Where(goqu.L("search_vector @@ "+tsQuery, query))
+
+	result := PagedCentersResult{}
+	if sql, args, err := builder.Select(goqu.COUNT("*")).ToSql(); err == nil {
+		if err := r.GetTX(ctx).Raw(sql, args...).Scan(&result.Count).Error; err != nil {
+			return result, err
+		}
+	} else {
+		return result, err
+	}
+
+	if result.Count == 0 {
+		return result, nil
+	}
+
+	resultsQuery := builder.
+		Select(goqu.L("centers.*")).
+		Order(
+			goqu.L("ts_rank(search_vector, "+tsQuery+")", query).Desc(),
+			goqu.I("centers.uuid").Asc(),
+		).
+		Offset(uint(page.Page * page.Size)).
+		Limit(uint(page.Size))
+
+	sql, args, err := resultsQuery.ToSql()
+	if err != nil {
+		return result, err
+	}
+
+	err = r.GetTX(ctx).Raw(sql, args...).
+		Preload("Operator").
+		Find(&result.Result).
+		Error
+
+	return result, err
+}
+
 func (r *centersRepository) Update(ctx context.Context, center domain.Center) (domain.Center, error) {
 	err := r.db.Save(&center).Error
 	return center, err
 }
 
-// FindByBounds finds centers within the given bounds
+// FindByBounds finds centers within the given bounds.
+//
+// If there are more than limit centers within the bounds, the result is thinned out using a searchGrid:
+// the centers of each grid cell are ranked by a hash of their uuid and the result is filled with the
+// first center of each cell, then the second one and so on. So the result is spread evenly over the bounds
+// and the same query always returns the same centers.
 func (r *centersRepository) FindByBounds(ctx context.Context, target domain.Bounds, params SearchParameters, limit uint) ([]domain.Center, error) {
-	// build base query restriction
-	builder := goqu.From("centers").Where(
+	grid := newSearchGrid(target, limit)
+	rankedQuery := r.buildSearchQuery(params).
+		Where(
+			goqu.I("latitude").Between(goqu.RangeVal{
+				Start: target.SouthWest.Latitude,
+				End:   target.NorthEast.Latitude,
+			}),
+			goqu.I("longitude").Between(goqu.RangeVal{
+				Start: target.SouthWest.Longitude,
+				End:   target.NorthEast.Longitude,
+			}),
+		).
+		Select(
+			goqu.L("centers.*"),
+			goqu.L("row_number() over (partition by floor(longitude / ?), floor(latitude / ?) order by md5(uuid))",
+				grid.CellSize, grid.CellSize).As("cell_rank"),
+		)
+
+	resultsQuery := goqu.From(rankedQuery.As("ranked")).
+		Order(goqu.I("cell_rank").Asc(), goqu.L("md5(uuid)").Asc()).
+		Limit(limit)
+
+	var result []domain.Center
+	sql, args, err := resultsQuery.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.GetTX(ctx).Raw(sql, args...).
+		Preload("Operator").
+		Find(&result).
+		Error
+
+	return result, err
+}
+
+// FindClusters aggregates the centers within the given bounds to clusters.
+// The clusters are calculated by grouping the centers by the cells of a grid depending on the zoom level.
+func (r *centersRepository) FindClusters(ctx context.Context, target domain.Bounds, params SearchParameters, zoom uint) ([]CenterCluster, error) {
+	grid := newClusterGrid(zoom)
+	clusterQuery := r.buildSearchQuery(params).
+		Where(
+			goqu.I("latitude").Between(goqu.RangeVal{
+				Start: target.SouthWest.Latitude,
+				End:   target.NorthEast.Latitude,
+			}),
+			goqu.I("longitude").Between(goqu.RangeVal{
+				Start: target.SouthWest.Longitude,
+				End:   target.NorthEast.Longitude,
+			}),
+		).
+		Select(
+			goqu.COUNT("*").As("count"),
+			goqu.AVG("latitude").As("latitude"),
+			goqu.AVG("longitude").As("longitude"),
+			goqu.MIN("latitude").As("min_latitude"),
+			goqu.MAX("latitude").As("max_latitude"),
+			goqu.MIN("longitude").As("min_longitude"),
+			goqu.MAX("longitude").As("max_longitude"),
+			countTestKind(domain.TestKindAntigen).As("antigen_count"),
+			countTestKind(domain.TestKindPCR).As("pcr_count"),
+			countTestKind(domain.TestKindVaccination).As("vaccination_count"),
+			countTestKind(domain.TestKindAntibody).As("antibody_count"),
+		).
+		GroupBy(
+			goqu.L("floor(longitude / ?)", grid.CellSize),
+			goqu.L("floor(latitude / ?)", grid.CellSize),
+		)
+
+	var result []CenterCluster
+	sql, args, err := clusterQuery.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.GetTX(ctx).Raw(sql, args...).
+		Scan(&result).
+		Error
+
+	return result, err
+}
+
+// FindNearby finds the centers within radius (in km) around the given location, ordered by their distance.
+// The radius is used as bounding box restriction before calculating the real distance, so the index on the
+// coordinates can be used.
+func (r *centersRepository) FindNearby(ctx context.Context, location domain.Coordinates, radius float64, params SearchParameters, limit uint) ([]domain.CenterWithDistance, error) {
+	latitudeDelta := radius / DistanceUnit
+	conditions := []goqu.Expression{
 		goqu.I("latitude").Between(goqu.RangeVal{
-			Start: target.SouthWest.Latitude,
-			End:   target.NorthEast.Latitude,
-		}),
-		goqu.I("longitude").Between(goqu.RangeVal{
-			Start: target.SouthWest.Longitude,
-			End:   target.NorthEast.Longitude,
+			Start: location.Latitude - latitudeDelta,
+			End:   location.Latitude + latitudeDelta,
 		}),
+		goqu.L("haversine(latitude, longitude, ?, ?) <= ?", location.Latitude, location.Longitude, radius),
+	}
+	if longitudeDelta, restricted := getLongitudeDelta(location, radius); restricted {
+		conditions = append(conditions, goqu.I("longitude").Between(goqu.RangeVal{
+			Start: location.Longitude - longitudeDelta,
+			End:   location.Longitude + longitudeDelta,
+		}))
+	}
+
+	distance := goqu.L("haversine(latitude, longitude, ?, ?)", location.Latitude, location.Longitude)
+	resultsQuery := r.buildSearchQuery(params).
+		Where(conditions...).
+		Select(goqu.L("centers.*"), distance.As("distance")).
+		Order(goqu.I("distance").Asc(), goqu.I("centers.uuid").Asc()).
+		Limit(limit)
+
+	var result []domain.CenterWithDistance
+	sql, args, err := resultsQuery.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.GetTX(ctx).Raw(sql, args...).
+		Preload("Operator").
+		Find(&result).
+		Error
+
+	return result, err
+}
+
+// getLongitudeDelta returns the longitude difference (in degrees) covering the radius (in km) around the location.
+// No delta is returned, if the radius reaches a pole or the antimeridian, as all longitudes must be searched then.
+func getLongitudeDelta(location domain.Coordinates, radius float64) (float64, bool) {
+	if math.Abs(location.Latitude)+radius/DistanceUnit >= 90 {
+		return 0, false
+	}
+
+	longitudeDelta := radius / (DistanceUnit * math.Cos(location.Latitude*math.Pi/180))
+	return longitudeDelta, math.Abs(location.Longitude)+longitudeDelta <= 180
+}
+
+// buildSearchQuery creates the base query for public center searches.
+// It restricts the result to visible centers, which are currently active and match the given search parameters.
+func (r *centersRepository) buildSearchQuery(params SearchParameters) *goqu.Dataset {
+	builder := goqu.From("centers").Where(
 		goqu.Or(
 			goqu.I("enter_date").IsNull(),
 			goqu.I("enter_date").Lte(time.Now()),
@@ -204,44 +478,61 @@ func (r *centersRepository) FindByBounds(ctx context.Context, target domain.Boun
 	if params.DCC != nil && *params.DCC {
 		builder = builder.Where(goqu.I("dcc").Eq(true))
 	}
-	if params.Appointment != nil {
-		builder = builder.Where(goqu.I("appointment").Eq(*params.Appointment))
+	if len(params.Appointments) > 0 {
+		appointments := make([]string, len(params.Appointments))
+		for i, appointment := range params.Appointments {
+			appointments[i] = string(appointment)
+		}
+		builder = builder.Where(goqu.I("appointment").In(appointments))
 	}
-	if params.TestKind != nil {
-		builder = builder.Where(goqu.L("test_kinds @> ARRAY[?]::varchar[]", *params.TestKind))
+	if len(params.TestKinds) > 0 {
+		if params.TestKindMatch == TestKindMatchAny {
+			builder = builder.Where(goqu.L("test_kinds && ?", varcharArray(domain.TestKinds(params.TestKinds).Strings())))
+		} else {
+			builder = builder.Where(goqu.L("test_kinds @> ?", varcharArray(domain.TestKinds(params.TestKinds).Strings())))
+		}
 	}
-	if params.IncludeOutdated == nil || *params.IncludeOutdated == false {
-		builder = builder.Where(goqu.L("last_update > now() - INTERVAL '4 weeks'"))
+	if len(params.Operators) > 0 {
+		builder = builder.Where(goqu.I("operator_uuid").In(params.Operators))
 	}
-
-	countQuery := builder.Select(goqu.COUNT("*"))
-	var count uint
-	if sql, args, err := countQuery.ToSql(); err == nil {
-		if err := r.db.Raw(sql, args).Scan(&count).Error; err != nil {
-			return nil, err
+	if len(params.Regions) > 0 {
+		regions := make([]string, len(params.Regions))
+		for i, region := range params.Regions {
+			regions[i] = strings.ToLower(region)
 		}
-	} else {
-		return nil, err
+		builder = builder.Where(goqu.L("lower(region) in ?", regions))
 	}
-
-	var result []domain.Center
-	if count == 0 {
-		return result, nil
+	if params.IncludeOutdated == nil || *params.IncludeOutdated == false {
+		builder = builder.Where(goqu.L("last_update > now() - INTERVAL '4 weeks'"))
 	}
+	if params.OpenAt != nil {
+		builder = builder.Where(openAtCondition(*params.OpenAt))
+	}
+	return builder
+}
 
-	fraction := float64(limit) / float64(count)
-	resultsQuery := builder.
-		Where(goqu.I("ranking").Lte(fraction)).
-		Select("centers.*"). //, goqu.L("haversine(latitude, longitude, ?, ?)", target.Latitude, target.Longitude).As("distance")).
-		Order(goqu.I("centers.uuid").Asc())
-
-	sql, args, err := resultsQuery.ToSql()
-	tx := r.db.Raw(sql, args...)
-	err = tx.Preload("Operator").
-		Find(&result).
-		Error
-
-	return result, err
+// openAtCondition creates a condition matching centers whose opening schedule contains an opening rule for the given time.
+// Like domain.OpeningSchedule.IsOpen rules for the specific date take precedence over holiday rules,
+// which take precedence over the weekday rules.
+func openAtCondition(t time.Time) goqu.LiteralExpression {
+	t = t.In(domain.OpeningHoursLocation)
+	minute := t.Hour()*60 + t.Minute()
+	dateKeys := domain.OpeningDateKeys(t)
+	return goqu.L(`exists (select 1 from jsonb_array_elements(centers.opening_schedule) as r
+		where not coalesce((r->>'closed')::boolean, false)
+		and (r->>'opens')::int <= ? and (r->>'closes')::int > ?
+		and case
+			when exists (select 1 from jsonb_array_elements(centers.opening_schedule) as d where d->>'date' in (?, ?))
+				then r->>'date' in (?, ?)
+			when ? and exists (select 1 from jsonb_array_elements(centers.opening_schedule) as h where (h->>'holidays')::boolean)
+				then coalesce((r->>'holidays')::boolean, false)
+			else coalesce(r->'weekdays' @> to_jsonb(?::int), false)
+		end)`,
+		minute, minute,
+		dateKeys[0], dateKeys[1],
+		dateKeys[0], dateKeys[1],
+		domain.IsPublicHoliday(t),
+		int(t.Weekday()))
 }
 
 func (r *centersRepository) FindByOperatorAndUserReference(ctx context.Context, operator, userReference string) (domain.Center, error) {
@@ -275,3 +566,19 @@ func (r *centersRepository) FindCentersForNotification(ctx context.Context, last
 
 	return result, err
 }
+
+// varcharArray creates a postgresql varchar array literal containing the given values
+func varcharArray(values []string) goqu.LiteralExpression {
+	placeholders := make([]string, len(values))
+	args := make([]interface{}, len(values))
+	for i, value := range values {
+		placeholders[i] = "?"
+		args[i] = value
+	}
+	return goqu.L("ARRAY["+strings.Join(placeholders, ", ")+"]::varchar[]", args...)
+}
+
+// countTestKind counts the rows supporting the given test kind
+func countTestKind(kind domain.TestKind) goqu.LiteralExpression {
+	return goqu.L("count(*) filter (where test_kinds @> ARRAY[?]::varchar[])", kind)
+}
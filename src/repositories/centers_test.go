@@ -0,0 +1,97 @@
+/*
+ *   Corona-Warn-App / cwa-map-backend
+ *
+ *   (C) 2020, T-Systems International GmbH
+ *
+ *   Deutsche Telekom AG and all other contributors /
+ *   copyright owners license this file to you under the Apache
+ *   License, Version 2.0 (the "License"); you may not use this
+ *   file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing,
+ *   software distributed under the License is distributed on an
+ *   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *   KIND, either express or implied.  See the License for the
+ *   specific language governing permissions and limitations
+ *   under the License.
+ */
+
+package repositories
+
+import (
+	"com.t-systems-mms.cwa/domain"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestOpenAtCondition(t *testing.T) {
+	tests := []struct {
+		name string
+		time string
+		// expected are the minute, the date keys, the holiday flag and the weekday
+		expected []interface{}
+	}{
+		{"weekday", "2021-03-15T09:00:00Z",
+			[]interface{}{600, "2021-03-15", "03-15", false, 1}},
+		{"summer time", "2021-07-05T06:30:00Z",
+			[]interface{}{510, "2021-07-05", "07-05", false, 1}},
+		{"next day in the local time zone", "2021-07-04T22:30:00Z",
+			[]interface{}{30, "2021-07-05", "07-05", false, 1}},
+		{"after switching to summer time", "2021-03-28T01:00:00Z",
+			[]interface{}{180, "2021-03-28", "03-28", false, 0}},
+		{"holiday", "2021-04-02T08:00:00Z",
+			[]interface{}{600, "2021-04-02", "04-02", true, 5}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			value, err := time.Parse(time.RFC3339, test.time)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expected := test.expected
+			assert.Equal(t, []interface{}{
+				expected[0], expected[0],
+				expected[1], expected[2],
+				expected[1], expected[2],
+				expected[3], expected[4],
+			}, openAtCondition(value).Args())
+		})
+	}
+}
+
+func TestGetLongitudeDelta(t *testing.T) {
+	tests := []struct {
+		name       string
+		location   domain.Coordinates
+		radius     float64
+		restricted bool
+	}{
+		{"equator", domain.Coordinates{Latitude: 0, Longitude: 10}, DistanceUnit, true},
+		{"germany", domain.Coordinates{Latitude: 51, Longitude: 10}, 20, true},
+		{"north pole", domain.Coordinates{Latitude: 90, Longitude: 10}, 20, false},
+		{"south pole", domain.Coordinates{Latitude: -90, Longitude: 10}, 20, false},
+		{"reaching pole", domain.Coordinates{Latitude: 89.9, Longitude: 10}, 20, false},
+		{"antimeridian", domain.Coordinates{Latitude: 0, Longitude: 179.9}, 20, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			delta, restricted := getLongitudeDelta(test.location, test.radius)
+			assert.Equal(t, test.restricted, restricted)
+			if restricted {
+				assert.False(t, math.IsInf(delta, 0) || math.IsNaN(delta))
+				assert.GreaterOrEqual(t, delta, test.radius/DistanceUnit)
+			}
+		})
+	}
+
+	delta, _ := getLongitudeDelta(domain.Coordinates{Latitude: 0}, DistanceUnit)
+	assert.InDelta(t, 1, delta, 1e-9)
+}
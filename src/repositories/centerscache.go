@@ -0,0 +1,501 @@
+/*
+ *   Corona-Warn-App / cwa-map-backend
+ *
+ *   (C) 2020, T-Systems International GmbH
+ *
+ *   Deutsche Telekom AG and all other contributors /
+ *   copyright owners license this file to you under the Apache
+ *   License, Version 2.0 (the "License"); you may not use this
+ *   file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing,
+ *   software distributed under the License is distributed on an
+ *   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *   KIND, either express or implied.  See the License for the
+ *   specific language governing permissions and limitations
+ *   under the License.
+ */
+
+package repositories
+
+import (
+	"com.t-systems-mms.cwa/domain"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// centersIndexCellSize is the size (in degrees) of the cells of the in-memory index
+	centersIndexCellSize = 0.25
+
+	// maxCenterAge is the age of the last update after which centers are treated as outdated
+	maxCenterAge = 4 * 7 * 24 * time.Hour
+
+	cacheUpdatesKey contextKey = "centersCacheUpdates"
+)
+
+type CentersCacheConfig struct {
+	Enabled bool
+
+	// RefreshInterval is the interval (in minutes) in which the cache is reloaded from the database
+	RefreshInterval int
+}
+
+// CentersCache is a Centers repository, which serves FindByBounds from an in-memory index of all visible centers.
+// All other methods are delegated to the underlying repository. Changes made through the cache are applied to the
+// index after the transaction has been committed, changes made in other ways become visible after the next refresh.
+type CentersCache struct {
+	Centers
+	config CentersCacheConfig
+	mutex  sync.RWMutex
+	index  *centersIndex
+
+	// reloading collects the changes applied while the cache is refreshed, which are missing in the reloaded centers
+	reloading *pendingCacheUpdates
+}
+
+// pendingCacheUpdates collects the changes made within a transaction
+type pendingCacheUpdates struct {
+	centers   []string
+	operators []string
+}
+
+func NewCentersCache(centers Centers, config CentersCacheConfig) *CentersCache {
+	return &CentersCache{
+		Centers: centers,
+		config:  config,
+	}
+}
+
+// RefreshScheduler loads all centers into the cache and reloads them periodically
+func (c *CentersCache) RefreshScheduler() {
+	logrus.WithFields(logrus.Fields{
+		"interval": c.config.RefreshInterval,
+	}).Info("Centers cache refresh scheduler started")
+	for {
+		if err := c.Refresh(); err != nil {
+			logrus.WithError(err).Error("Error refreshing centers cache")
+		}
+		time.Sleep(time.Duration(c.config.RefreshInterval) * time.Minute)
+	}
+}
+
+// Refresh reloads all centers from the database.
+// Changes applied while reloading are applied again to the reloaded centers, as they may be missing.
+func (c *CentersCache) Refresh() error {
+	c.mutex.Lock()
+	if c.reloading == nil {
+		c.reloading = &pendingCacheUpdates{}
+	}
+	c.mutex.Unlock()
+
+	centers, err := c.Centers.FindAll()
+	if err != nil {
+		c.mutex.Lock()
+		c.reloading = nil
+		c.mutex.Unlock()
+		return err
+	}
+
+	index := newCentersIndex()
+	for i := range centers {
+		index.Put(centers[i])
+	}
+
+	c.mutex.Lock()
+	c.index = index
+	updates := c.reloading
+	c.reloading = nil
+	c.mutex.Unlock()
+	c.apply(updates)
+
+	logrus.WithFields(logrus.Fields{
+		"count": index.Len(),
+	}).Debug("Centers cache refreshed")
+	return nil
+}
+
+func (c *CentersCache) UseTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, hasUpdates := ctx.Value(cacheUpdatesKey).(*pendingCacheUpdates); hasUpdates {
+		return c.Centers.UseTransaction(ctx, fn)
+	}
+
+	updates := &pendingCacheUpdates{}
+	if err := c.Centers.UseTransaction(context.WithValue(ctx, cacheUpdatesKey, updates), fn); err != nil {
+		return err
+	}
+	c.apply(updates)
+	return nil
+}
+
+func (c *CentersCache) FindByBounds(ctx context.Context, target domain.Bounds, params SearchParameters, limit uint) ([]domain.Center, error) {
+	c.mutex.RLock()
+	if c.index == nil {
+		// not loaded yet, the lock must not be held while querying the database
+		c.mutex.RUnlock()
+		return c.Centers.FindByBounds(ctx, target, params, limit)
+	}
+
+	filter := newCenterFilter(params)
+	candidates := c.index.FindByBounds(target, filter.Matches)
+	c.mutex.RUnlock()
+
+	// the entries of the index are replaced instead of being modified, so they can be used without the lock
+	return sampleCenters(candidates, newSearchGrid(target, limit), limit), nil
+}
+
+func (c *CentersCache) Save(ctx context.Context, center *domain.Center) error {
+	if err := c.Centers.Save(ctx, center); err != nil {
+		return err
+	}
+	c.update(ctx, &pendingCacheUpdates{centers: []string{center.UUID}})
+	return nil
+}
+
+func (c *CentersCache) Restore(ctx context.Context, center *domain.Center) error {
+	if err := c.Centers.Restore(ctx, center); err != nil {
+		return err
+	}
+	c.update(ctx, &pendingCacheUpdates{centers: []string{center.UUID}})
+	return nil
+}
+
+func (c *CentersCache) SaveMultiple(ctx context.Context, centers []domain.Center) ([]domain.Center, error) {
+	result, err := c.Centers.SaveMultiple(ctx, centers)
+	if err != nil {
+		return result, err
+	}
+
+	updates := &pendingCacheUpdates{}
+	for _, center := range result {
+		updates.centers = append(updates.centers, center.UUID)
+	}
+	c.update(ctx, updates)
+	return result, nil
+}
+
+func (c *CentersCache) Delete(ctx context.Context, center domain.Center) error {
+	if err := c.Centers.Delete(ctx, center); err != nil {
+		return err
+	}
+	c.update(ctx, &pendingCacheUpdates{centers: []string{center.UUID}})
+	return nil
+}
+
+func (c *CentersCache) DeleteByOperator(ctx context.Context, operator string) error {
+	if err := c.Centers.DeleteByOperator(ctx, operator); err != nil {
+		return err
+	}
+	c.update(ctx, &pendingCacheUpdates{operators: []string{operator}})
+	return nil
+}
+
+// update applies the given changes immediately or collects them until the current transaction has been committed
+func (c *CentersCache) update(ctx context.Context, updates *pendingCacheUpdates) {
+	if pending, hasUpdates := ctx.Value(cacheUpdatesKey).(*pendingCacheUpdates); hasUpdates {
+		pending.centers = append(pending.centers, updates.centers...)
+		pending.operators = append(pending.operators, updates.operators...)
+		return
+	}
+	c.apply(updates)
+}
+
+// apply reloads the changed centers from the database and updates the index.
+// Deleted operators are applied first, so centers saved again after deleting all centers of an operator are kept.
+func (c *CentersCache) apply(updates *pendingCacheUpdates) {
+	if len(updates.centers) == 0 && len(updates.operators) == 0 {
+		return
+	}
+
+	centers := make([]domain.Center, 0, len(updates.centers))
+	deleted := make([]string, 0)
+	for _, uuid := range updates.centers {
+		center, err := c.Centers.FindByUUID(context.Background(), uuid)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			deleted = append(deleted, uuid)
+		} else if err != nil {
+			logrus.WithError(err).WithField("center", uuid).Warn("Error updating centers cache")
+		} else {
+			centers = append(centers, center)
+		}
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.reloading != nil {
+		c.reloading.centers = append(c.reloading.centers, updates.centers...)
+		c.reloading.operators = append(c.reloading.operators, updates.operators...)
+	}
+	if c.index == nil {
+		return
+	}
+
+	for _, operator := range updates.operators {
+		c.index.DeleteByOperator(operator)
+	}
+	for _, uuid := range deleted {
+		c.index.Delete(uuid)
+	}
+	for i := range centers {
+		c.index.Put(centers[i])
+	}
+}
+
+type indexCell struct {
+	X, Y int
+}
+
+// indexedCenter is a center within the index, together with the md5 hash of its uuid used for sampling
+type indexedCenter struct {
+	domain.Center
+	hash string
+	cell indexCell
+}
+
+// centersIndex is a grid based spatial index of the visible centers
+type centersIndex struct {
+	centers   map[string]*indexedCenter
+	cells     map[indexCell]map[string]*indexedCenter
+	operators map[string]*domain.Operator
+}
+
+func newCentersIndex() *centersIndex {
+	return &centersIndex{
+		centers:   make(map[string]*indexedCenter),
+		cells:     make(map[indexCell]map[string]*indexedCenter),
+		operators: make(map[string]*domain.Operator),
+	}
+}
+
+func (i *centersIndex) Len() int {
+	return len(i.centers)
+}
+
+// Put adds the center to the index or replaces it. Invisible centers are removed from the index.
+func (i *centersIndex) Put(center domain.Center) {
+	i.Delete(center.UUID)
+	if center.Visible != nil && !*center.Visible {
+		return
+	}
+
+	// share the operators between all centers
+	if center.Operator != nil {
+		i.operators[center.OperatorUUID] = center.Operator
+	}
+	center.Operator = i.operators[center.OperatorUUID]
+
+	hash := md5.Sum([]byte(center.UUID))
+	entry := &indexedCenter{
+		Center: center,
+		hash:   hex.EncodeToString(hash[:]),
+		cell:   i.cellOf(center.Longitude, center.Latitude),
+	}
+
+	i.centers[center.UUID] = entry
+	if _, hasCell := i.cells[entry.cell]; !hasCell {
+		i.cells[entry.cell] = make(map[string]*indexedCenter)
+	}
+	i.cells[entry.cell][center.UUID] = entry
+}
+
+func (i *centersIndex) Delete(uuid string) {
+	entry, exists := i.centers[uuid]
+	if !exists {
+		return
+	}
+
+	delete(i.centers, uuid)
+	delete(i.cells[entry.cell], uuid)
+	if len(i.cells[entry.cell]) == 0 {
+		delete(i.cells, entry.cell)
+	}
+}
+
+func (i *centersIndex) DeleteByOperator(operator string) {
+	for uuid, entry := range i.centers {
+		if entry.OperatorUUID == operator {
+			i.Delete(uuid)
+		}
+	}
+}
+
+// FindByBounds returns all centers within the bounds accepted by the given filter
+func (i *centersIndex) FindByBounds(target domain.Bounds, filter func(center *domain.Center) bool) []*indexedCenter {
+	min := i.cellOf(target.SouthWest.Longitude, target.SouthWest.Latitude)
+	max := i.cellOf(target.NorthEast.Longitude, target.NorthEast.Latitude)
+
+	result := make([]*indexedCenter, 0)
+	collect := func(cell map[string]*indexedCenter) {
+		for _, entry := range cell {
+			if entry.Latitude >= target.SouthWest.Latitude && entry.Latitude <= target.NorthEast.Latitude &&
+				entry.Longitude >= target.SouthWest.Longitude && entry.Longitude <= target.NorthEast.Longitude &&
+				filter(&entry.Center) {
+				result = append(result, entry)
+			}
+		}
+	}
+
+	cellCount := (max.X - min.X + 1) * (max.Y - min.Y + 1)
+	if cellCount > len(i.cells) {
+		// for large bounds it is faster to scan all existing cells
+		for key, cell := range i.cells {
+			if key.X >= min.X && key.X <= max.X && key.Y >= min.Y && key.Y <= max.Y {
+				collect(cell)
+			}
+		}
+		return result
+	}
+
+	for x := min.X; x <= max.X; x++ {
+		for y := min.Y; y <= max.Y; y++ {
+			collect(i.cells[indexCell{X: x, Y: y}])
+		}
+	}
+	return result
+}
+
+func (i *centersIndex) cellOf(longitude, latitude float64) indexCell {
+	return indexCell{
+		X: int(math.Floor(longitude / centersIndexCellSize)),
+		Y: int(math.Floor(latitude / centersIndexCellSize)),
+	}
+}
+
+// sampleCenters selects at most limit centers spread over the cells of the grid, like FindByBounds of the
+// postgresql repository does: the centers are ranked within their cell by the md5 hash of their uuid and
+// the centers with the lowest ranks are selected.
+func sampleCenters(candidates []*indexedCenter, grid searchGrid, limit uint) []domain.Center {
+	sort.Slice(candidates, func(a, b int) bool {
+		return candidates[a].hash < candidates[b].hash
+	})
+
+	ranks := make(map[indexCell]int)
+	cellRanks := make([]int, len(candidates))
+	for i, candidate := range candidates {
+		cell := indexCell{
+			X: int(math.Floor(candidate.Longitude / grid.CellSize)),
+			Y: int(math.Floor(candidate.Latitude / grid.CellSize)),
+		}
+		ranks[cell]++
+		cellRanks[i] = ranks[cell]
+	}
+
+	order := make([]int, len(candidates))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return cellRanks[order[a]] < cellRanks[order[b]]
+	})
+
+	if uint(len(order)) > limit {
+		order = order[:limit]
+	}
+
+	result := make([]domain.Center, len(order))
+	for i, candidate := range order {
+		result[i] = candidates[candidate].Center
+	}
+	return result
+}
+
+// centerFilter evaluates the SearchParameters in memory, like buildSearchQuery
+type centerFilter struct {
+	params  SearchParameters
+	now     time.Time
+	regions map[string]bool
+}
+
+func newCenterFilter(params SearchParameters) centerFilter {
+	filter := centerFilter{params: params, now: time.Now()}
+	if len(params.Regions) > 0 {
+		filter.regions = make(map[string]bool)
+		for _, region := range params.Regions {
+			filter.regions[strings.ToLower(region)] = true
+		}
+	}
+	return filter
+}
+
+func (f centerFilter) Matches(center *domain.Center) bool {
+	params := f.params
+	if (center.EnterDate != nil && center.EnterDate.After(f.now)) ||
+		(center.LeaveDate != nil && center.LeaveDate.Before(f.now)) {
+		return false
+	}
+
+	if params.DCC != nil && *params.DCC && (center.DCC == nil || !*center.DCC) {
+		return false
+	}
+
+	if len(params.Appointments) > 0 && !f.matchesAppointment(center) {
+		return false
+	}
+
+	if len(params.TestKinds) > 0 && !f.matchesTestKinds(center) {
+		return false
+	}
+
+	if len(params.Operators) > 0 && !containsString(params.Operators, center.OperatorUUID) {
+		return false
+	}
+
+	if f.regions != nil && (center.Region == nil || !f.regions[strings.ToLower(*center.Region)]) {
+		return false
+	}
+
+	if (params.IncludeOutdated == nil || !*params.IncludeOutdated) &&
+		(center.LastUpdate == nil || f.now.Sub(*center.LastUpdate) >= maxCenterAge) {
+		return false
+	}
+
+	if params.OpenAt != nil && !center.OpeningSchedule.IsOpen(*params.OpenAt) {
+		return false
+	}
+	return true
+}
+
+func (f centerFilter) matchesAppointment(center *domain.Center) bool {
+	if center.Appointment == nil {
+		return false
+	}
+	for _, appointment := range f.params.Appointments {
+		if appointment == *center.Appointment {
+			return true
+		}
+	}
+	return false
+}
+
+func (f centerFilter) matchesTestKinds(center *domain.Center) bool {
+	for _, kind := range f.params.TestKinds {
+		found := containsString(center.TestKinds, string(kind))
+		if found && f.params.TestKindMatch == TestKindMatchAny {
+			return true
+		} else if !found && f.params.TestKindMatch != TestKindMatchAny {
+			return false
+		}
+	}
+	return f.params.TestKindMatch != TestKindMatchAny
+}
+
+func containsString(values []string, value string) bool {
+	for _, entry := range values {
+		if entry == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+/*
+ *   Corona-Warn-App / cwa-map-backend
+ *
+ *   (C) 2020, T-Systems International GmbH
+ *
+ *   Deutsche Telekom AG and all other contributors /
+ *   copyright owners license this file to you under the Apache
+ *   License, Version 2.0 (the "License"); you may not use this
+ *   file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing,
+ *   software distributed under the License is distributed on an
+ *   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *   KIND, either express or implied.  See the License for the
+ *   specific language governing permissions and limitations
+ *   under the License.
+ */
+
+package repositories
+
+import (
+	"com.t-systems-mms.cwa/domain"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"testing"
+)
+
+// memoryCenters keeps the centers in memory, all other methods are not implemented
+type memoryCenters struct {
+	Centers
+	centers map[string]domain.Center
+
+	// reloading is called by FindAll after the centers have been copied
+	reloading func()
+}
+
+func (r *memoryCenters) FindAll() ([]domain.Center, error) {
+	result := make([]domain.Center, 0, len(r.centers))
+	for _, center := range r.centers {
+		result = append(result, center)
+	}
+	if r.reloading != nil {
+		r.reloading()
+	}
+	return result, nil
+}
+
+func (r *memoryCenters) FindByUUID(_ context.Context, uuid string) (domain.Center, error) {
+	if center, found := r.centers[uuid]; found {
+		return center, nil
+	}
+	return domain.Center{}, gorm.ErrRecordNotFound
+}
+
+func (r *memoryCenters) Save(_ context.Context, center *domain.Center) error {
+	r.centers[center.UUID] = *center
+	return nil
+}
+
+func (r *memoryCenters) Delete(_ context.Context, center domain.Center) error {
+	delete(r.centers, center.UUID)
+	return nil
+}
+
+func TestCentersCacheRefreshReplaysChanges(t *testing.T) {
+	repository := &memoryCenters{centers: map[string]domain.Center{
+		"deleted": {UUID: "deleted"},
+		"updated": {UUID: "updated", Name: "before"},
+	}}
+	cache := NewCentersCache(repository, CentersCacheConfig{Enabled: true})
+	require.NoError(t, cache.Refresh())
+
+	// changes made while the centers are reloaded are missing in the reloaded centers
+	repository.reloading = func() {
+		repository.reloading = nil
+		ctx := context.Background()
+		require.NoError(t, cache.Save(ctx, &domain.Center{UUID: "updated", Name: "after"}))
+		require.NoError(t, cache.Save(ctx, &domain.Center{UUID: "created"}))
+		require.NoError(t, cache.Delete(ctx, domain.Center{UUID: "deleted"}))
+	}
+	require.NoError(t, cache.Refresh())
+
+	assert.Equal(t, 2, cache.index.Len())
+	assert.Equal(t, "after", cache.index.centers["updated"].Name)
+	assert.Contains(t, cache.index.centers, "created")
+	assert.NotContains(t, cache.index.centers, "deleted")
+	assert.Nil(t, cache.reloading)
+}
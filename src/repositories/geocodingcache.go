@@ -0,0 +1,129 @@
+/*
+ *   Corona-Warn-App / cwa-map-backend
+ *
+ *   (C) 2020, T-Systems International GmbH
+ *
+ *   Deutsche Telekom AG and all other contributors /
+ *   copyright owners license this file to you under the Apache
+ *   License, Version 2.0 (the "License"); you may not use this
+ *   file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing,
+ *   software distributed under the License is distributed on an
+ *   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *   KIND, either express or implied.  See the License for the
+ *   specific language governing permissions and limitations
+ *   under the License.
+ */
+
+package repositories
+
+import (
+	"com.t-systems-mms.cwa/domain"
+	"com.t-systems-mms.cwa/external/geocoding"
+	"context"
+	"encoding/json"
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+const (
+	geocodingStatusOk             = "ok"
+	geocodingStatusNoResult       = "no_result"
+	geocodingStatusTooManyResults = "too_many_results"
+)
+
+type GeocodingCache interface {
+	Repository
+	geocoding.ResultCache
+
+	// Purge deletes the cache entries created before the given time, or all entries if before is nil.
+	// If address is not empty, only the entry of this address is deleted.
+	Purge(ctx context.Context, address string, before *time.Time) (int64, error)
+}
+
+type geocodingCacheRepository struct {
+	postgresqlRepository
+}
+
+func NewGeocodingCacheRepository(db *gorm.DB) GeocodingCache {
+	return &geocodingCacheRepository{
+		postgresqlRepository{db: db},
+	}
+}
+
+// GetResult returns the cached result. The cache is not part of any transaction, so results are
+// kept even if the transaction of the geocoded center is rolled back.
+func (r *geocodingCacheRepository) GetResult(ctx context.Context, address string) (geocoding.CacheEntry, bool, error) {
+	var entry domain.GeocodingCacheEntry
+	err := r.db.WithContext(ctx).
+		Where("address = ?", address).
+		First(&entry).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return geocoding.CacheEntry{}, false, nil
+	} else if err != nil {
+		return geocoding.CacheEntry{}, false, err
+	}
+
+	result := geocoding.CacheEntry{Created: entry.Created}
+	switch entry.Status {
+	case geocodingStatusNoResult:
+		result.Err = geocoding.ErrNoResult
+	case geocodingStatusTooManyResults:
+		result.Err = geocoding.ErrTooManyResults
+	default:
+		if entry.Result == nil {
+			return result, false, nil
+		}
+		if err := json.Unmarshal([]byte(*entry.Result), &result.Result); err != nil {
+			return result, false, err
+		}
+	}
+	return result, true, nil
+}
+
+func (r *geocodingCacheRepository) PutResult(ctx context.Context, address string, entry geocoding.CacheEntry) error {
+	cacheEntry := domain.GeocodingCacheEntry{
+		Address: address,
+		Status:  geocodingStatusOk,
+		Created: entry.Created,
+	}
+
+	switch entry.Err {
+	case nil:
+		result, err := json.Marshal(entry.Result)
+		if err != nil {
+			return err
+		}
+		tmp := string(result)
+		cacheEntry.Result = &tmp
+	case geocoding.ErrNoResult:
+		cacheEntry.Status = geocodingStatusNoResult
+	case geocoding.ErrTooManyResults:
+		cacheEntry.Status = geocodingStatusTooManyResults
+	default:
+		return entry.Err
+	}
+
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{UpdateAll: true}).
+		Create(&cacheEntry).Error
+}
+
+func (r *geocodingCacheRepository) Purge(ctx context.Context, address string, before *time.Time) (int64, error) {
+	query := r.GetTX(ctx).Session(&gorm.Session{AllowGlobalUpdate: true})
+	if address != "" {
+		query = query.Where("address = ?", geocoding.NormalizeAddress(address))
+	}
+	if before != nil {
+		query = query.Where("created < ?", *before)
+	}
+
+	result := query.Delete(&domain.GeocodingCacheEntry{})
+	return result.RowsAffected, result.Error
+}
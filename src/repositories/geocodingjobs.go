@@ -0,0 +1,120 @@
+/*
+ *   Corona-Warn-App / cwa-map-backend
+ *
+ *   (C) 2020, T-Systems International GmbH
+ *
+ *   Deutsche Telekom AG and all other contributors /
+ *   copyright owners license this file to you under the Apache
+ *   License, Version 2.0 (the "License"); you may not use this
+ *   file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing,
+ *   software distributed under the License is distributed on an
+ *   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *   KIND, either express or implied.  See the License for the
+ *   specific language governing permissions and limitations
+ *   under the License.
+ */
+
+package repositories
+
+import (
+	"com.t-systems-mms.cwa/domain"
+	"context"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type GeocodingJobs interface {
+	Repository
+
+	// Enqueue creates jobs for geocoding the given centers. Existing jobs of the centers are reset.
+	Enqueue(ctx context.Context, centers ...string) error
+
+	// Claim locks at most limit jobs, which are due, for the given lease time and increments their attempts.
+	// Jobs locked by another worker are skipped, so multiple instances can process the jobs concurrently.
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]domain.GeocodingJob, error)
+
+	// Complete deletes the claimed job. If the job has been enqueued again in the meantime, it is kept.
+	Complete(ctx context.Context, job domain.GeocodingJob) error
+
+	// Retry releases the claimed job, so it is processed again at nextAttempt
+	Retry(ctx context.Context, job domain.GeocodingJob, nextAttempt time.Time, lastError string) error
+}
+
+type geocodingJobsRepository struct {
+	postgresqlRepository
+}
+
+func NewGeocodingJobsRepository(db *gorm.DB) GeocodingJobs {
+	return &geocodingJobsRepository{
+		postgresqlRepository{db: db},
+	}
+}
+
+func (r *geocodingJobsRepository) Enqueue(ctx context.Context, centers ...string) error {
+	if len(centers) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	jobs := make([]domain.GeocodingJob, len(centers))
+	for i, center := range centers {
+		jobs[i] = domain.GeocodingJob{
+			CenterUUID:  center,
+			NextAttempt: now,
+			Created:     now,
+		}
+	}
+
+	return r.GetTX(ctx).
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "center_uuid"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"attempts":     0,
+				"next_attempt": now,
+				"locked_until": nil,
+				"last_error":   nil,
+			}),
+		}).
+		CreateInBatches(jobs, 1000).Error
+}
+
+func (r *geocodingJobsRepository) Claim(ctx context.Context, limit int, lease time.Duration) ([]domain.GeocodingJob, error) {
+	var jobs []domain.GeocodingJob
+	err := r.GetTX(ctx).Raw(`update geocoding_jobs
+		set locked_until = now() + make_interval(secs => ?), attempts = attempts + 1
+		where center_uuid in (
+			select center_uuid from geocoding_jobs
+			where next_attempt <= now() and (locked_until is null or locked_until < now())
+			order by next_attempt
+			limit ?
+			for update skip locked)
+		returning *`, lease.Seconds(), limit).
+		Scan(&jobs).Error
+	return jobs, err
+}
+
+func (r *geocodingJobsRepository) Complete(ctx context.Context, job domain.GeocodingJob) error {
+	return r.GetTX(ctx).
+		Where("center_uuid = ? and locked_until = ?", job.CenterUUID, job.LockedUntil).
+		Delete(&domain.GeocodingJob{}).Error
+}
+
+func (r *geocodingJobsRepository) Retry(ctx context.Context, job domain.GeocodingJob, nextAttempt time.Time, lastError string) error {
+	if len(lastError) > 512 {
+		lastError = lastError[:512]
+	}
+
+	return r.GetTX(ctx).Model(&domain.GeocodingJob{}).
+		Where("center_uuid = ? and locked_until = ?", job.CenterUUID, job.LockedUntil).
+		Updates(map[string]interface{}{
+			"next_attempt": nextAttempt,
+			"locked_until": nil,
+			"last_error":   lastError,
+		}).Error
+}
@@ -0,0 +1,142 @@
+/*
+ *   Corona-Warn-App / cwa-map-backend
+ *
+ *   (C) 2020, T-Systems International GmbH
+ *
+ *   Deutsche Telekom AG and all other contributors /
+ *   copyright owners license this file to you under the Apache
+ *   License, Version 2.0 (the "License"); you may not use this
+ *   file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing,
+ *   software distributed under the License is distributed on an
+ *   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *   KIND, either express or implied.  See the License for the
+ *   specific language governing permissions and limitations
+ *   under the License.
+ */
+
+package repositories
+
+import (
+	"com.t-systems-mms.cwa/domain"
+	"context"
+	"gorm.io/gorm"
+	"time"
+)
+
+type PagedImportJobsResult struct {
+	PagedResult
+	Result []domain.ImportJob
+}
+
+type ImportJobs interface {
+	Repository
+
+	// Save persists the given job
+	Save(ctx context.Context, job *domain.ImportJob) error
+
+	// FindByOperatorAndUUID finds the job of the operator without its centers
+	FindByOperatorAndUUID(ctx context.Context, operator, uuid string) (domain.ImportJob, error)
+
+	// FindByOperator finds the jobs of the operator without their centers, the latest jobs first
+	FindByOperator(ctx context.Context, operator string, page PageRequest) (PagedImportJobsResult, error)
+
+	// Claim locks the oldest pending job, or a running job whose worker did not extend the lease,
+	// for the given lease time and marks it as running. It returns nil, if there is no job.
+	Claim(ctx context.Context, lease time.Duration) (*domain.ImportJob, error)
+
+	// UpdateProgress saves the counters, errors and status of the claimed job and extends its lease
+	UpdateProgress(ctx context.Context, job *domain.ImportJob, lease time.Duration) error
+
+	// RenewLease extends the lease of the running job, without changing its progress
+	RenewLease(ctx context.Context, uuid string, lease time.Duration) error
+
+	// DeleteFinishedBefore deletes the completed and failed jobs finished before the given time
+	DeleteFinishedBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+type importJobsRepository struct {
+	postgresqlRepository
+}
+
+func NewImportJobsRepository(db *gorm.DB) ImportJobs {
+	return &importJobsRepository{
+		postgresqlRepository{db: db},
+	}
+}
+
+func (r *importJobsRepository) Save(ctx context.Context, job *domain.ImportJob) error {
+	return r.GetTX(ctx).Save(job).Error
+}
+
+func (r *importJobsRepository) FindByOperatorAndUUID(ctx context.Context, operator, uuid string) (domain.ImportJob, error) {
+	var result domain.ImportJob
+	err := r.GetTX(ctx).Omit("centers").
+		Where("operator_uuid = ? and uuid = ?", operator, uuid).
+		Take(&result).Error
+	return result, err
+}
+
+func (r *importJobsRepository) FindByOperator(ctx context.Context, operator string, page PageRequest) (PagedImportJobsResult, error) {
+	baseQuery := r.GetTX(ctx).Model(&domain.ImportJob{}).
+		Where("operator_uuid = ?", operator)
+
+	result := PagedImportJobsResult{}
+	if err := baseQuery.Count(&result.Count).Error; err != nil {
+		return result, err
+	}
+
+	err := baseQuery.
+		Omit("centers").
+		Order("created desc").
+		Offset(page.Page * page.Size).
+		Limit(page.Size).
+		Find(&result.Result).
+		Error
+
+	return result, err
+}
+
+func (r *importJobsRepository) Claim(ctx context.Context, lease time.Duration) (*domain.ImportJob, error) {
+	var jobs []domain.ImportJob
+	err := r.GetTX(ctx).Raw(`update import_jobs
+		set status = ?, locked_until = now() + make_interval(secs => ?), started = coalesce(started, now())
+		where uuid in (
+			select uuid from import_jobs
+			where status = ? or (status = ? and locked_until < now())
+			order by created
+			limit 1
+			for update skip locked)
+		returning *`, domain.ImportJobRunning, lease.Seconds(), domain.ImportJobPending, domain.ImportJobRunning).
+		Scan(&jobs).Error
+	if err != nil || len(jobs) == 0 {
+		return nil, err
+	}
+	return &jobs[0], nil
+}
+
+func (r *importJobsRepository) UpdateProgress(ctx context.Context, job *domain.ImportJob, lease time.Duration) error {
+	lockedUntil := time.Now().Add(lease)
+	job.LockedUntil = &lockedUntil
+	return r.GetTX(ctx).Model(job).
+		Select("status", "processed", "succeeded", "failed", "errors", "error", "finished", "locked_until").
+		Updates(job).Error
+}
+
+func (r *importJobsRepository) RenewLease(ctx context.Context, uuid string, lease time.Duration) error {
+	return r.GetTX(ctx).Model(&domain.ImportJob{}).
+		Where("uuid = ? and status = ?", uuid, domain.ImportJobRunning).
+		Update("locked_until", gorm.Expr("now() + make_interval(secs => ?)", lease.Seconds())).Error
+}
+
+func (r *importJobsRepository) DeleteFinishedBefore(ctx context.Context, before time.Time) (int64, error) {
+	result := r.GetTX(ctx).
+		Where("status in ? and finished < ?",
+			[]domain.ImportJobStatus{domain.ImportJobCompleted, domain.ImportJobFailed}, before).
+		Delete(&domain.ImportJob{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,89 @@
+/*
+ *   Corona-Warn-App / cwa-map-backend
+ *
+ *   (C) 2020, T-Systems International GmbH
+ *
+ *   Deutsche Telekom AG and all other contributors /
+ *   copyright owners license this file to you under the Apache
+ *   License, Version 2.0 (the "License"); you may not use this
+ *   file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing,
+ *   software distributed under the License is distributed on an
+ *   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *   KIND, either express or implied.  See the License for the
+ *   specific language governing permissions and limitations
+ *   under the License.
+ */
+
+package repositories
+
+import (
+	"com.t-systems-mms.cwa/core/util"
+	"com.t-systems-mms.cwa/domain"
+	"context"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ImportProfiles interface {
+	Repository
+
+	// FindAll finds all profiles ordered by their name
+	FindAll(ctx context.Context) ([]domain.ImportProfile, error)
+
+	FindByUUID(ctx context.Context, uuid string) (domain.ImportProfile, error)
+
+	FindByName(ctx context.Context, name string) (domain.ImportProfile, error)
+
+	// Save creates or replaces the profile, a new uuid is assigned if it has none
+	Save(ctx context.Context, profile *domain.ImportProfile) error
+
+	Delete(ctx context.Context, uuid string) error
+}
+
+type importProfilesRepository struct {
+	postgresqlRepository
+}
+
+func NewImportProfilesRepository(db *gorm.DB) ImportProfiles {
+	return &importProfilesRepository{
+		postgresqlRepository{db: db},
+	}
+}
+
+func (r *importProfilesRepository) FindAll(ctx context.Context) ([]domain.ImportProfile, error) {
+	var profiles []domain.ImportProfile
+	err := r.GetTX(ctx).Order("name").Find(&profiles).Error
+	return profiles, err
+}
+
+func (r *importProfilesRepository) FindByUUID(ctx context.Context, uuid string) (domain.ImportProfile, error) {
+	var profile domain.ImportProfile
+	err := r.GetTX(ctx).Where("uuid = ?", uuid).First(&profile).Error
+	return profile, err
+}
+
+func (r *importProfilesRepository) FindByName(ctx context.Context, name string) (domain.ImportProfile, error) {
+	var profile domain.ImportProfile
+	err := r.GetTX(ctx).Where("name = ?", name).First(&profile).Error
+	return profile, err
+}
+
+func (r *importProfilesRepository) Save(ctx context.Context, profile *domain.ImportProfile) error {
+	if util.IsNilOrEmpty(&profile.UUID) {
+		newUUID, err := uuid.NewUUID()
+		if err != nil {
+			return err
+		}
+		profile.UUID = newUUID.String()
+	}
+	return r.GetTX(ctx).Save(profile).Error
+}
+
+func (r *importProfilesRepository) Delete(ctx context.Context, uuid string) error {
+	return r.GetTX(ctx).Delete(&domain.ImportProfile{UUID: uuid}).Error
+}
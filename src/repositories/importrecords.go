@@ -0,0 +1,91 @@
+/*
+ *   Corona-Warn-App / cwa-map-backend
+ *
+ *   (C) 2020, T-Systems International GmbH
+ *
+ *   Deutsche Telekom AG and all other contributors /
+ *   copyright owners license this file to you under the Apache
+ *   License, Version 2.0 (the "License"); you may not use this
+ *   file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing,
+ *   software distributed under the License is distributed on an
+ *   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *   KIND, either express or implied.  See the License for the
+ *   specific language governing permissions and limitations
+ *   under the License.
+ */
+
+package repositories
+
+import (
+	"com.t-systems-mms.cwa/domain"
+	"context"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PagedImportRecordsResult struct {
+	PagedResult
+	Result []domain.ImportRecord
+}
+
+type ImportRecords interface {
+	Repository
+
+	// Save persists the given record
+	Save(ctx context.Context, record *domain.ImportRecord) error
+
+	// FindByOperator finds the import history of the operator without the snapshots, the latest imports first
+	FindByOperator(ctx context.Context, operator string, page PageRequest) (PagedImportRecordsResult, error)
+
+	// FindLatestByOperator finds the latest import of the operator and locks it until the end of the transaction
+	FindLatestByOperator(ctx context.Context, operator string) (domain.ImportRecord, error)
+}
+
+type importRecordsRepository struct {
+	postgresqlRepository
+}
+
+func NewImportRecordsRepository(db *gorm.DB) ImportRecords {
+	return &importRecordsRepository{
+		postgresqlRepository{db: db},
+	}
+}
+
+func (r *importRecordsRepository) Save(ctx context.Context, record *domain.ImportRecord) error {
+	return r.GetTX(ctx).Save(record).Error
+}
+
+func (r *importRecordsRepository) FindByOperator(ctx context.Context, operator string, page PageRequest) (PagedImportRecordsResult, error) {
+	baseQuery := r.GetTX(ctx).Model(&domain.ImportRecord{}).
+		Where("operator_uuid = ?", operator)
+
+	result := PagedImportRecordsResult{}
+	if err := baseQuery.Count(&result.Count).Error; err != nil {
+		return result, err
+	}
+
+	err := baseQuery.
+		Omit("snapshot").
+		Order("imported desc").
+		Offset(page.Page * page.Size).
+		Limit(page.Size).
+		Find(&result.Result).
+		Error
+
+	return result, err
+}
+
+func (r *importRecordsRepository) FindLatestByOperator(ctx context.Context, operator string) (domain.ImportRecord, error) {
+	var result domain.ImportRecord
+	err := r.GetTX(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("operator_uuid = ?", operator).
+		Order("imported desc").
+		Take(&result).Error
+	return result, err
+}
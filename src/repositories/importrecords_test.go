@@ -0,0 +1,66 @@
+/*
+ *   Corona-Warn-App / cwa-map-backend
+ *
+ *   (C) 2020, T-Systems International GmbH
+ *
+ *   Deutsche Telekom AG and all other contributors /
+ *   copyright owners license this file to you under the Apache
+ *   License, Version 2.0 (the "License"); you may not use this
+ *   file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing,
+ *   software distributed under the License is distributed on an
+ *   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *   KIND, either express or implied.  See the License for the
+ *   specific language governing permissions and limitations
+ *   under the License.
+ */
+
+package repositories
+
+import (
+	"com.t-systems-mms.cwa/domain"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"testing"
+)
+
+// openDryRunDB opens a database, which only builds the statements without executing them.
+// The returned function returns the last statement.
+func openDryRunDB(t *testing.T) (*gorm.DB, func() string) {
+	db, err := gorm.Open(postgres.Open("host=localhost"), &gorm.Config{DryRun: true, DisableAutomaticPing: true, SkipDefaultTransaction: true})
+	require.NoError(t, err)
+
+	var statement string
+	capture := func(tx *gorm.DB) {
+		statement = tx.Statement.SQL.String()
+	}
+	require.NoError(t, db.Callback().Query().After("gorm:query").Register("test:capture", capture))
+	require.NoError(t, db.Callback().Create().After("gorm:create").Register("test:capture", capture))
+	return db, func() string {
+		return statement
+	}
+}
+
+func TestFindLatestImportRecordLocksRecord(t *testing.T) {
+	db, statement := openDryRunDB(t)
+
+	_, _ = NewImportRecordsRepository(db).FindLatestByOperator(context.Background(), "operator")
+	assert.Regexp(t, `WHERE operator_uuid = \$1 ORDER BY imported desc LIMIT 1 FOR UPDATE$`, statement())
+}
+
+func TestRestoreCenterReplacesExistingCenter(t *testing.T) {
+	db, statement := openDryRunDB(t)
+
+	center := domain.Center{UUID: "center", OperatorUUID: "operator", Operator: &domain.Operator{UUID: "operator"}}
+	require.NoError(t, NewCentersRepository(db).Restore(context.Background(), &center))
+	assert.Regexp(t, `^INSERT INTO "centers" .* ON CONFLICT \("uuid"\) DO UPDATE SET .*"operator_uuid"="excluded"."operator_uuid"`,
+		statement())
+	assert.NotContains(t, statement(), `"operators"`)
+}
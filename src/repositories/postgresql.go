@@ -23,13 +23,31 @@ package repositories
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"gorm.io/gorm"
+	"sync/atomic"
 )
 
-const transactionKey = "transactionKey"
+// contextKey is the type of the keys of context values set by the repositories,
+// so they don't collide with values of other packages
+type contextKey string
+
+const transactionKey contextKey = "transactionKey"
+
+// ErrTransactionAborted is returned by UseSavepoint, if the savepoint could not be used and
+// the transaction can not be continued
+var ErrTransactionAborted = errors.New("transaction aborted")
+
+// savepointCounter is used for unique savepoint names
+var savepointCounter uint64
 
 type Repository interface {
 	UseTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+
+	// UseSavepoint runs fn within a savepoint of the current transaction, so errors of fn only roll back
+	// the changes of fn and the transaction can be continued. Without a transaction it is like UseTransaction.
+	UseSavepoint(ctx context.Context, fn func(ctx context.Context) error) error
 }
 
 type postgresqlRepository struct {
@@ -55,6 +73,26 @@ func (r *postgresqlRepository) UseTransaction(ctx context.Context, fn func(ctx c
 	return nil
 }
 
+func (r *postgresqlRepository) UseSavepoint(ctx context.Context, fn func(ctx context.Context) error) error {
+	tx, hasTx := ctx.Value(transactionKey).(*gorm.DB)
+	if !hasTx {
+		return r.UseTransaction(ctx, fn)
+	}
+
+	name := fmt.Sprintf("savepoint_%d", atomic.AddUint64(&savepointCounter, 1))
+	if err := tx.SavePoint(name).Error; err != nil {
+		return fmt.Errorf("%w: %s", ErrTransactionAborted, err.Error())
+	}
+
+	if err := fn(ctx); err != nil {
+		if rollbackErr := tx.RollbackTo(name).Error; rollbackErr != nil {
+			return fmt.Errorf("%w: %s", ErrTransactionAborted, rollbackErr.Error())
+		}
+		return err
+	}
+	return tx.Exec("RELEASE SAVEPOINT " + name).Error
+}
+
 func (r *postgresqlRepository) GetTX(ctx context.Context) *gorm.DB {
 	tx, hasTx := ctx.Value(transactionKey).(*gorm.DB)
 	if hasTx {
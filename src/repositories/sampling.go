@@ -0,0 +1,70 @@
+/*
+ *   Corona-Warn-App / cwa-map-backend
+ *
+ *   (C) 2020, T-Systems International GmbH
+ *
+ *   Deutsche Telekom AG and all other contributors /
+ *   copyright owners license this file to you under the Apache
+ *   License, Version 2.0 (the "License"); you may not use this
+ *   file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing,
+ *   software distributed under the License is distributed on an
+ *   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *   KIND, either express or implied.  See the License for the
+ *   specific language governing permissions and limitations
+ *   under the License.
+ */
+
+package repositories
+
+import (
+	"com.t-systems-mms.cwa/domain"
+	"math"
+)
+
+const (
+	// minCellSize is the smallest cell size (in degrees) used for sampling, which is roughly 10 meters
+	minCellSize = 360.0 / (1 << 22)
+
+	// clusterCellsPerTile is the count of cluster cells per map tile and axis, so a 256px tile
+	// is divided into cells of 64px
+	clusterCellsPerTile = 4
+
+	// MaxClusterZoom is the highest supported zoom level for clustering
+	MaxClusterZoom = 20
+)
+
+// searchGrid divides the world into equally sized square cells, which are used to spread the results
+// of a search evenly over the searched bounds.
+//
+// The grid is aligned to the world and not to the searched bounds, and the cell size is always a
+// power-of-two fraction of 360 degrees (like the zoom levels of the map). So moving the bounds without
+// zooming keeps the cells unchanged and the same centers are selected for the same cells.
+type searchGrid struct {
+	CellSize float64
+}
+
+// newSearchGrid creates the grid for the given bounds, so that the bounds are covered by at least limit cells.
+func newSearchGrid(bounds domain.Bounds, limit uint) searchGrid {
+	width := math.Abs(bounds.NorthEast.Longitude - bounds.SouthWest.Longitude)
+	height := math.Abs(bounds.NorthEast.Latitude - bounds.SouthWest.Latitude)
+	if limit == 0 || width*height == 0 {
+		return searchGrid{CellSize: minCellSize}
+	}
+
+	targetSize := math.Sqrt(width * height / float64(limit))
+	cellSize := 360 / math.Pow(2, math.Ceil(math.Log2(360/targetSize)))
+	return searchGrid{CellSize: math.Max(cellSize, minCellSize)}
+}
+
+// newClusterGrid creates the grid used for clustering centers at the given zoom level
+func newClusterGrid(zoom uint) searchGrid {
+	if zoom > MaxClusterZoom {
+		zoom = MaxClusterZoom
+	}
+	return searchGrid{CellSize: 360 / float64(uint(1)<<zoom) / clusterCellsPerTile}
+}
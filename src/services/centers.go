@@ -34,7 +34,6 @@ import (
 	"github.com/go-playground/validator"
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
-	"math/rand"
 	"time"
 )
 
@@ -42,6 +41,69 @@ type ImportCenterResult struct {
 	Center   domain.Center
 	Warnings []string
 	Errors   []string
+
+	// Line is the line (csv) or row (spreadsheets) of the center in the import file, starting at 1
+	Line int
+
+	// Skipped is set for malformed lines, which could not be parsed. The errors contain the cause.
+	Skipped bool
+}
+
+// ImportSummary contains the counts of the data rows of an import file, header rows are not counted
+type ImportSummary struct {
+	Rows         int
+	Empty        int
+	Skipped      int
+	Valid        int
+	Invalid      int
+	WithWarnings int
+}
+
+// CountResults counts the valid and invalid results and the results with warnings.
+// It must be called again, if warnings or errors are added to the results.
+func (s *ImportSummary) CountResults(results []ImportCenterResult) {
+	s.Valid, s.Invalid, s.WithWarnings = 0, 0, 0
+	for _, result := range results {
+		if result.Skipped {
+			continue
+		}
+
+		if len(result.Errors) > 0 {
+			s.Invalid++
+		} else {
+			s.Valid++
+		}
+		if len(result.Warnings) > 0 {
+			s.WithWarnings++
+		}
+	}
+}
+
+// ImportItemResult is the result of a single center of a partial import
+type ImportItemResult struct {
+	// Center is the saved center, or the center like it was given if Error is set
+	Center domain.Center
+	Error  error
+}
+
+// ImportOptions describes an import of centers for an operator, independent of the authenticated user
+type ImportOptions struct {
+	OperatorUUID string
+
+	// User is the name of the user importing the centers, it is stored in the import history
+	User string
+
+	// AllowDCC keeps the DCC flag of the centers, it replaces the role of the authenticated user
+	AllowDCC bool
+
+	// DeleteAll deletes all centers of the operator before saving the centers
+	DeleteAll bool
+
+	// Partial saves the valid centers, even if other centers fail, see ImportCentersPartial
+	Partial bool
+
+	// Progress is called after every importProgressInterval centers with the results so far
+	Progress func(results []ImportItemResult)
 }
 
 type CentersServiceConfig struct {
@@ -50,19 +112,64 @@ type CentersServiceConfig struct {
 	RenotifyInterval     int
 }
 
+// importProgressInterval is the count of centers after which the progress of an import is reported
+const importProgressInterval = 100
+
 var (
 	ErrDuplicateUserReference = core.ApplicationError("duplicate user reference")
+
+	// ErrNothingToRollback is returned, if the last import of an operator has already been rolled back
+	ErrNothingToRollback = core.ApplicationError("nothing to roll back")
+
+	// ErrModifiedSinceImport is returned, if centers of the last import have been changed after the import,
+	// so rolling back the import would discard these changes
+	ErrModifiedSinceImport = core.ApplicationError("centers have been modified since the import")
+
+	// errNothingImported rolls back partial imports without any saved center
+	errNothingImported = errors.New("nothing imported")
 )
 
 type Centers interface {
 	ImportCenters(ctx context.Context, centers []domain.Center, deleteAll bool) ([]domain.Center, error)
+
+	// ImportCentersPartial saves all valid centers and returns the error for each center, which could not be saved.
+	// If no center could be saved, nothing is changed, so the existing centers are not deleted.
+	ImportCentersPartial(ctx context.Context, centers []domain.Center, deleteAll bool) ([]ImportItemResult, error)
+
+	// ImportCentersWithOptions imports the centers like ImportCenters or ImportCentersPartial,
+	// but without an authenticated user, e.g. for import jobs
+	ImportCentersWithOptions(ctx context.Context, centers []domain.Center, options ImportOptions) ([]ImportItemResult, error)
+
+	// RollbackLastImport restores the centers of the operator changed by the last import from its snapshot
+	// and deletes the centers created by the import. Changes of these centers after the import are lost.
+	RollbackLastImport(ctx context.Context, operatorUUID string) (domain.ImportRecord, error)
+
+	// DiffImport returns the changes ImportCenters would apply, without saving anything
+	DiffImport(ctx context.Context, centers []domain.Center, deleteAll bool) (ImportDiff, error)
+
 	Save(ctx context.Context, center *domain.Center, geocoding bool) error
-	PerformGeocoding(ctx context.Context, centers []domain.Center)
+
+	// Geocode geocodes the center with the given uuid and saves it.
+	// Errors of the geocoding provider are returned, except missing or ambiguous results,
+	// which are stored in the message of the center.
+	Geocode(ctx context.Context, uuid string) error
+
+	// GeocodingFailed marks the geocoding of the center with the given uuid as failed, after all attempts failed
+	GeocodingFailed(ctx context.Context, uuid string, cause error) error
+
+	// EnqueueGeocoding creates jobs for geocoding the given centers in the background
+	EnqueueGeocoding(ctx context.Context, centers []domain.Center) error
+
+	// UpdateOpeningSchedules parses the opening hours of all centers again and
+	// returns the count of centers with unparsable opening hours
+	UpdateOpeningSchedules(ctx context.Context) (int, error)
 	CenterNotificationScheduler()
 }
 
 type centersService struct {
 	centersRepository repositories.Centers
+	geocodingJobs     repositories.GeocodingJobs
+	importRecords     repositories.ImportRecords
 	operators         repositories.Operators
 	operatorsService  Operators
 	geocoder          geocoding.Geocoder
@@ -71,12 +178,14 @@ type centersService struct {
 	config            CentersServiceConfig
 }
 
-func NewCentersService(centersRepository repositories.Centers, config CentersServiceConfig, operators repositories.Operators, operatorsService Operators, geocoder geocoding.Geocoder, mailService MailService) Centers {
+func NewCentersService(centersRepository repositories.Centers, geocodingJobs repositories.GeocodingJobs, importRecords repositories.ImportRecords, config CentersServiceConfig, operators repositories.Operators, operatorsService Operators, geocoder geocoding.Geocoder, mailService MailService) Centers {
 	validate := validator.New()
 	validate.RegisterTagNameFunc(util.JsonTagNameFunc)
 
 	return &centersService{
 		centersRepository: centersRepository,
+		geocodingJobs:     geocodingJobs,
+		importRecords:     importRecords,
 		operators:         operators,
 		operatorsService:  operatorsService,
 		geocoder:          geocoder,
@@ -91,37 +200,39 @@ func (s *centersService) Save(ctx context.Context, center *domain.Center, geocod
 	if err != nil {
 		return err
 	}
+	return s.save(ctx, operator.UUID, security.HasRole(ctx, security.RoleDCC), center, geocoding)
+}
 
+// save saves the center for the given operator, the DCC flag is reset unless allowDCC is set
+func (s *centersService) save(ctx context.Context, operatorUUID string, allowDCC bool, center *domain.Center, geocoding bool) error {
 	if err := s.validate.Struct(center); err != nil {
 		return err
 	}
 
-	if !security.HasRole(ctx, security.RoleDCC) {
+	if !allowDCC {
 		tmp := false
 		center.DCC = &tmp
 	}
 
-	if util.IsNotNilOrEmpty(center.UserReference) {
-		if existing, err := s.centersRepository.FindByOperatorAndUserReference(ctx, operator.UUID, *center.UserReference); err == nil {
-			if util.IsNotNilOrEmpty(&center.UUID) && existing.UUID != center.UUID {
-				return ErrDuplicateUserReference
-			}
-
-			// If there is already a center with this userReference, use its UUID to replace it
-			center.UUID = existing.UUID
-		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
-			return err
-		}
+	if existing, err := s.findReplacedCenter(ctx, operatorUUID, center); err != nil {
+		return err
+	} else if existing != nil {
+		// If there is already a center with this userReference, use its UUID to replace it
+		center.UUID = existing.UUID
 	}
 
-	center.OperatorUUID = operator.UUID
-	center.Ranking = rand.Float64()
+	center.OperatorUUID = operatorUUID
+	center.OpeningSchedule, _ = domain.ParseOpeningHours(center.OpeningHours)
+	center.GeocodingStatus = statusPtr(domain.GeocodingPending)
 
 	tmpNow := time.Now()
 	center.LastUpdate = &tmpNow
 	if err := s.centersRepository.Save(ctx, center); err == nil {
 		if geocoding {
-			return s.GeocodeCenter(ctx, center)
+			if err := s.GeocodeCenter(ctx, center); err != nil {
+				// retry in the background
+				return s.geocodingJobs.Enqueue(ctx, center.UUID)
+			}
 		}
 		return nil
 	} else {
@@ -129,42 +240,154 @@ func (s *centersService) Save(ctx context.Context, center *domain.Center, geocod
 	}
 }
 
+// findReplacedCenter returns the existing center of the operator, which is replaced by saving the given center.
+// Centers are only matched by their user reference, nil is returned if there is no such center.
+func (s *centersService) findReplacedCenter(ctx context.Context, operatorUUID string, center *domain.Center) (*domain.Center, error) {
+	if util.IsNilOrEmpty(center.UserReference) {
+		return nil, nil
+	}
+
+	existing, err := s.centersRepository.FindByOperatorAndUserReference(ctx, operatorUUID, *center.UserReference)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if util.IsNotNilOrEmpty(&center.UUID) && existing.UUID != center.UUID {
+		return nil, ErrDuplicateUserReference
+	}
+	return &existing, nil
+}
+
 func (s *centersService) ImportCenters(ctx context.Context, centers []domain.Center, deleteAll bool) ([]domain.Center, error) {
-	// validate each center before
-	for _, center := range centers {
-		if err := s.validate.Struct(center); err != nil {
-			return nil, err
-		}
+	operator, err := s.operatorsService.GetCurrentOperator(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := s.ImportCentersWithOptions(ctx, centers, ImportOptions{
+		OperatorUUID: operator.UUID,
+		User:         security.GetUsername(ctx),
+		AllowDCC:     security.HasRole(ctx, security.RoleDCC),
+		DeleteAll:    deleteAll,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range results {
+		centers[i] = results[i].Center
 	}
+	return centers, nil
+}
 
+func (s *centersService) ImportCentersPartial(ctx context.Context, centers []domain.Center, deleteAll bool) ([]ImportItemResult, error) {
 	operator, err := s.operatorsService.GetCurrentOperator(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	err = s.centersRepository.UseTransaction(ctx, func(ctx context.Context) error {
-		if deleteAll {
-			if err := s.centersRepository.DeleteByOperator(ctx, operator.UUID); err != nil {
+	return s.ImportCentersWithOptions(ctx, centers, ImportOptions{
+		OperatorUUID: operator.UUID,
+		User:         security.GetUsername(ctx),
+		AllowDCC:     security.HasRole(ctx, security.RoleDCC),
+		DeleteAll:    deleteAll,
+		Partial:      true,
+	})
+}
+
+func (s *centersService) ImportCentersWithOptions(ctx context.Context, centers []domain.Center, options ImportOptions) ([]ImportItemResult, error) {
+	if !options.Partial {
+		// validate each center before
+		for _, center := range centers {
+			if err := s.validate.Struct(center); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	results := make([]ImportItemResult, len(centers))
+	err := s.centersRepository.UseTransaction(ctx, func(ctx context.Context) error {
+		// the centers before the import are used for the snapshot of the import history
+		existing, err := s.centersRepository.FindAllByOperator(ctx, options.OperatorUUID)
+		if err != nil {
+			return err
+		}
+
+		if options.DeleteAll {
+			if err := s.centersRepository.DeleteByOperator(ctx, options.OperatorUUID); err != nil {
 				return err
 			}
 		}
 
-		for i, _ := range centers {
-			if err := s.Save(ctx, &centers[i], false); err != nil {
+		imported := make([]domain.Center, 0, len(centers))
+		for i := range centers {
+			results[i].Center = centers[i]
+			if options.Partial {
+				if err := s.importCenterPartially(ctx, &results[i], options); err != nil {
+					return err
+				}
+			} else if err := s.save(ctx, options.OperatorUUID, options.AllowDCC, &results[i].Center, false); err != nil {
 				return err
 			}
+
+			if results[i].Error == nil {
+				imported = append(imported, results[i].Center)
+			}
+			if options.Progress != nil && (i+1)%importProgressInterval == 0 {
+				options.Progress(results[:i+1])
+			}
 		}
 
-		return nil
+		if options.Partial && len(imported) == 0 {
+			return errNothingImported
+		}
+
+		record := newImportRecord(options, existing, imported)
+		if err := s.importRecords.Save(ctx, &record); err != nil {
+			return err
+		}
+		return s.EnqueueGeocoding(ctx, imported)
 	})
-	if err != nil {
+	if err != nil && err != errNothingImported {
 		return nil, err
 	}
 
-	go s.PerformGeocoding(context.Background(), centers)
-	return centers, err
+	logrus.WithFields(logrus.Fields{
+		"operator": options.OperatorUUID,
+		"count":    len(centers),
+		"partial":  options.Partial,
+	}).Info("Imported centers")
+	return results, nil
+}
+
+// importCenterPartially saves the center of the result within a savepoint, so failing centers don't abort
+// the transaction. The error of the center is stored in the result, only errors of the transaction are returned.
+func (s *centersService) importCenterPartially(ctx context.Context, result *ImportItemResult, options ImportOptions) error {
+	if err := s.validate.Struct(result.Center); err != nil {
+		result.Error = err
+		return nil
+	}
+
+	center := result.Center
+	err := s.centersRepository.UseSavepoint(ctx, func(ctx context.Context) error {
+		return s.save(ctx, options.OperatorUUID, options.AllowDCC, &center, false)
+	})
+	if errors.Is(err, repositories.ErrTransactionAborted) {
+		return err
+	} else if err != nil {
+		result.Error = err
+		return nil
+	}
+
+	result.Center = center
+	return nil
 }
 
+// GeocodeCenter geocodes and saves the given center.
+// Errors of the geocoding provider are returned without saving the center, except missing or ambiguous results,
+// which are stored in the message of the center.
 func (s *centersService) GeocodeCenter(ctx context.Context, center *domain.Center) error {
 	logrus.WithFields(logrus.Fields{
 		"center":  center.UUID,
@@ -181,14 +404,27 @@ func (s *centersService) GeocodeCenter(ctx context.Context, center *domain.Cente
 			WithError(err).
 			Error("Error geocoding center")
 
-		if err == geocoding.ErrTooManyResults || err == geocoding.ErrNoResult {
-			msg := fmt.Sprintf("Geocoding: %s", err.Error())
-			center.Message = &msg
+		if err != geocoding.ErrTooManyResults && err != geocoding.ErrNoResult {
+			return err
+		}
+
+		msg := fmt.Sprintf("Geocoding: %s", err.Error())
+		center.Message = &msg
+		if err == geocoding.ErrTooManyResults {
+			center.GeocodingStatus = statusPtr(domain.GeocodingAmbiguous)
+		} else {
+			center.GeocodingStatus = statusPtr(domain.GeocodingFailed)
 		}
 	} else {
 		center.Zip = &g.Zip
 		center.Region = &g.Region
-		if !center.Coordinates.Fixed {
+		center.GeocodingLocationType = &g.LocationType
+		center.GeocodingAddress = &g.Address
+		center.GeocodingProvider = &g.Provider
+		if center.Coordinates.Fixed {
+			center.GeocodingStatus = statusPtr(domain.GeocodingManual)
+		} else {
+			center.GeocodingStatus = statusPtr(domain.GeocodingOk)
 			center.Coordinates = domain.Coordinates{
 				Longitude: g.Coordinates.Longitude,
 				Latitude:  g.Coordinates.Latitude,
@@ -196,34 +432,76 @@ func (s *centersService) GeocodeCenter(ctx context.Context, center *domain.Cente
 		}
 	}
 
-	err = s.centersRepository.Save(context.Background(), center)
+	err = s.centersRepository.Save(ctx, center)
 	if err != nil {
 		logrus.WithError(err).Error("Error saving center")
 	}
 	return err
 }
 
-func (s *centersService) PerformGeocoding(ctx context.Context, centers []domain.Center) {
+func (s *centersService) Geocode(ctx context.Context, uuid string) error {
+	center, err := s.centersRepository.FindByUUID(ctx, uuid)
+	if err != nil {
+		return err
+	}
+	return s.GeocodeCenter(ctx, &center)
+}
+
+func (s *centersService) GeocodingFailed(ctx context.Context, uuid string, cause error) error {
+	center, err := s.centersRepository.FindByUUID(ctx, uuid)
+	if err != nil {
+		return err
+	}
+
+	msg := fmt.Sprintf("Geocoding: %s", cause.Error())
+	center.Message = &msg
+	if center.Coordinates.Fixed {
+		center.GeocodingStatus = statusPtr(domain.GeocodingManual)
+	} else {
+		center.GeocodingStatus = statusPtr(domain.GeocodingFailed)
+	}
+	return s.centersRepository.Save(ctx, &center)
+}
+
+func (s *centersService) EnqueueGeocoding(ctx context.Context, centers []domain.Center) error {
+	uuids := make([]string, len(centers))
+	for i, center := range centers {
+		uuids[i] = center.UUID
+	}
+
 	logrus.WithFields(logrus.Fields{
 		"count": len(centers),
-	}).Info("Starting geocoding of importet centers")
+	}).Info("Enqueue geocoding of centers")
+	return s.geocodingJobs.Enqueue(ctx, uuids...)
+}
 
-	for _, center := range centers {
-		uuid := center.UUID
-		center, err := s.centersRepository.FindByUUID(context.Background(), center.UUID)
-		if err != nil {
-			logrus.
-				WithFields(logrus.Fields{"center": uuid}).
-				WithError(err).
-				Warn("Center to found, maybe already deleted")
-			continue
+func (s *centersService) UpdateOpeningSchedules(ctx context.Context) (int, error) {
+	centers, err := s.centersRepository.FindAll()
+	if err != nil {
+		return 0, err
+	}
+
+	invalidCount := 0
+	err = s.centersRepository.UseTransaction(ctx, func(ctx context.Context) error {
+		for i := range centers {
+			var warnings []string
+			centers[i].OpeningSchedule, warnings = domain.ParseOpeningHours(centers[i].OpeningHours)
+			if len(warnings) > 0 {
+				invalidCount++
+			}
+
+			if err := s.centersRepository.Save(ctx, &centers[i]); err != nil {
+				return err
+			}
 		}
+		return nil
+	})
 
-		_ = s.GeocodeCenter(ctx, &center)
-	}
 	logrus.WithFields(logrus.Fields{
-		"count": len(centers),
-	}).Info("Geocoding for centers completed")
+		"count":   len(centers),
+		"invalid": invalidCount,
+	}).Info("Updated opening schedules of centers")
+	return invalidCount, err
 }
 
 func (s *centersService) ProcessCenterNotification(ctx context.Context, center domain.Center) error {
@@ -277,3 +555,7 @@ func (s *centersService) CenterNotificationScheduler() {
 		time.Sleep(time.Duration(s.config.NotificationInterval) * time.Hour)
 	}
 }
+
+func statusPtr(status domain.GeocodingStatus) *domain.GeocodingStatus {
+	return &status
+}
@@ -22,6 +22,7 @@
 package services
 
 import (
+	"bytes"
 	"com.t-systems-mms.cwa/core/util"
 	"com.t-systems-mms.cwa/domain"
 	"encoding/csv"
@@ -30,111 +31,196 @@ import (
 	"github.com/go-playground/validator"
 	"github.com/lib/pq"
 	"github.com/sirupsen/logrus"
+	"golang.org/x/text/encoding/charmap"
 	"io"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 )
 
-const (
-	partnerIdIndex     = "Partner ID"
-	userReferenceIndex = "NR."
-	nameIndex          = "Name der Teststelle"
-	operatorNameIndex  = "Name des Betreibers"
-	labIdIndex         = "Lab ID"
-	streetIndex        = "Straße"
-	houseNumberIndex   = "Hausnr."
-	postalCodeIndex    = "PLZ"
-	cityIndex          = "Ort"
-	enterDateIndex     = "Eintrittsdatum"
-	leaveDateIndex     = "Austrittsdatum"
-	emailIndex         = "E-Mail"
-	openingHoursIndex  = "Öffnungszeiten"
-	appointmentIndex   = "Terminbuchung"
-	testKindsIndex     = "Testmöglichkeiten"
-	websiteIndex       = "Webseite"
-	dccIndex           = "Ausstellung eines Dicital Covid Zertifikates (DCC)"
-	noteIndex          = "Adresshinweis"
-	visibleIndex       = "Sichtbar"
-	latitudeIndex      = "Breitengrad"
-	longitudeIndex     = "Längengrad"
-)
+// utf8BOM is the byte order mark written by Excel at the beginning of csv files saved as UTF-8
+const utf8BOM = "\xef\xbb\xbf"
+
+// DefaultImportProfile is the format of the import template, which is used if no other profile has been selected
+var DefaultImportProfile = domain.ImportProfile{
+	Name:        "default",
+	Delimiter:   ";",
+	HeaderRows:  2,
+	DateFormats: []string{"_2.1.2006"},
+	TrueValues:  []string{"ja"},
+	Columns: domain.ImportColumns{
+		domain.ImportFieldPartnerId:     {"Partner ID"},
+		domain.ImportFieldUserReference: {"NR."},
+		domain.ImportFieldName:          {"Name der Teststelle"},
+		domain.ImportFieldOperatorName:  {"Name des Betreibers"},
+		domain.ImportFieldLabId:         {"Lab ID"},
+		domain.ImportFieldStreet:        {"Straße"},
+		domain.ImportFieldHouseNumber:   {"Hausnr."},
+		domain.ImportFieldZip:           {"PLZ"},
+		domain.ImportFieldCity:          {"Ort"},
+		domain.ImportFieldEnterDate:     {"Eintrittsdatum"},
+		domain.ImportFieldLeaveDate:     {"Austrittsdatum"},
+		domain.ImportFieldEmail:         {"E-Mail"},
+		domain.ImportFieldOpeningHours:  {"Öffnungszeiten"},
+		domain.ImportFieldAppointment:   {"Terminbuchung"},
+		domain.ImportFieldTestKinds:     {"Testmöglichkeiten"},
+		domain.ImportFieldWebsite:       {"Webseite"},
+		domain.ImportFieldDCC: {"Ausstellung eines Dicital Covid Zertifikates (DCC)",
+			"Ausstellung eines Digital Covid Zertifikates (DCC)"},
+		domain.ImportFieldAddressNote: {"Adresshinweis"},
+		domain.ImportFieldVisible:     {"Sichtbar"},
+		domain.ImportFieldLatitude:    {"Breitengrad"},
+		domain.ImportFieldLongitude:   {"Längengrad"},
+	},
+}
 
 const (
 	fieldNotFound = -1
 	fieldRequired = -2
 )
 
+// validatedImportFields contains the import fields of the validated fields of domain.Center
+var validatedImportFields = map[string]string{
+	"Name":         domain.ImportFieldName,
+	"Website":      domain.ImportFieldWebsite,
+	"Address":      domain.ImportFieldStreet,
+	"OpeningHours": domain.ImportFieldOpeningHours,
+	"Appointment":  domain.ImportFieldAppointment,
+	"Email":        domain.ImportFieldEmail,
+}
+
+// CsvParser parses center import files in the format described by an import profile
 type CsvParser struct {
+	profile domain.ImportProfile
+}
+
+func NewCsvParser(profile domain.ImportProfile) *CsvParser {
+	return &CsvParser{profile: profile}
 }
 
-func (c *CsvParser) Parse(reader io.Reader) ([]ImportCenterResult, error) {
+// rowReader reads the rows of an import file, it returns io.EOF after the last row
+type rowReader interface {
+	Read() ([]string, error)
+
+	// Line returns the line of the last row read, starting at 1
+	Line() int
+}
+
+// csvRowReader reads the rows of a csv file, rows may span several lines
+type csvRowReader struct {
+	*csv.Reader
+}
+
+func (r csvRowReader) Line() int {
+	line, _ := r.FieldPos(0)
+	return line
+}
+
+// Parse parses a csv file using the delimiter of the profile, see decodeCSV for the supported encodings.
+// Malformed lines are skipped and returned as results with errors.
+func (c *CsvParser) Parse(reader io.Reader) ([]ImportCenterResult, ImportSummary, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, ImportSummary{}, err
+	}
+	data, err = decodeCSV(data)
+	if err != nil {
+		return nil, ImportSummary{}, err
+	}
+
+	csvReader := csv.NewReader(bytes.NewReader(data))
+	csvReader.Comma = []rune(c.profile.Delimiter)[0]
+	csvReader.FieldsPerRecord = -1
+	return c.parseRows(csvRowReader{csvReader})
+}
+
+// decodeCSV returns the content of a csv file as UTF-8 without byte order mark.
+// Files which are not valid UTF-8 are decoded as Windows-1252, which is used by Excel for csv files by default.
+func decodeCSV(data []byte) ([]byte, error) {
+	if utf8.Valid(data) {
+		return bytes.TrimPrefix(data, []byte(utf8BOM)), nil
+	}
+	return charmap.Windows1252.NewDecoder().Bytes(data)
+}
+
+func (c *CsvParser) parseRows(reader rowReader) ([]ImportCenterResult, ImportSummary, error) {
 	result := make([]ImportCenterResult, 0)
+	summary := ImportSummary{}
 
 	validate := validator.New()
 
-	csvReader := csv.NewReader(reader)
-	csvReader.Comma = ';'
-	csvReader.FieldsPerRecord = -1
+	columnMappings := make(map[string]int)
+	for _, field := range domain.ImportFields {
+		columnMappings[field] = fieldNotFound
+	}
+	for _, field := range domain.RequiredImportFields {
+		columnMappings[field] = fieldRequired
+	}
 
-	columnMappings := map[string]int{
-		partnerIdIndex:     fieldNotFound,
-		userReferenceIndex: fieldNotFound,
-		nameIndex:          fieldRequired, // required
-		labIdIndex:         fieldNotFound,
-		operatorNameIndex:  fieldNotFound,
-		streetIndex:        fieldRequired, // required
-		houseNumberIndex:   fieldNotFound,
-		postalCodeIndex:    fieldNotFound,
-		cityIndex:          fieldNotFound,
-		enterDateIndex:     fieldNotFound,
-		leaveDateIndex:     fieldNotFound,
-		emailIndex:         fieldRequired, // required
-		openingHoursIndex:  fieldNotFound,
-		appointmentIndex:   fieldNotFound,
-		testKindsIndex:     fieldNotFound,
-		websiteIndex:       fieldNotFound,
-		dccIndex:           fieldNotFound,
-		noteIndex:          fieldNotFound,
-		visibleIndex:       fieldNotFound,
-		latitudeIndex:      fieldNotFound,
-		longitudeIndex:     fieldNotFound,
+	// fields by their lower case column names
+	columnFields := make(map[string]string)
+	for field, names := range c.profile.Columns {
+		for _, name := range names {
+			columnFields[strings.ToLower(strings.TrimSpace(name))] = field
+		}
 	}
 
+	// column names of the fields, like they are used in the file
+	columnNames := make(map[string]string)
+
 	headerRows := 0
+	columnCount := 0
 	for {
-		entry, err := csvReader.Read()
+		entry, err := reader.Read()
 		if err == io.EOF {
 			break
 		}
-		if _, isParseError := err.(*csv.ParseError); isParseError {
+		if parseError, isParseError := err.(*csv.ParseError); isParseError {
+			if headerRows < c.profile.HeaderRows {
+				return nil, summary, err
+			}
+
+			summary.Rows++
+			summary.Skipped++
+			result = append(result, ImportCenterResult{
+				Line:    parseError.StartLine,
+				Skipped: true,
+				Errors: []string{fmt.Sprintf("line %d skipped, column %d: %s",
+					parseError.Line, parseError.Column, parseError.Err.Error())},
+			})
 			continue
 		} else if err != nil {
-			return nil, err
+			return nil, summary, err
 		}
 
-		if headerRows < 2 {
+		if headerRows < c.profile.HeaderRows {
 			headerRows = headerRows + 1
 			for i, v := range entry {
-				if _, ok := columnMappings[strings.TrimSpace(v)]; ok {
-					columnMappings[strings.TrimSpace(v)] = i
+				if field, ok := columnFields[strings.ToLower(strings.TrimSpace(v))]; ok {
+					columnMappings[field] = i
+					columnNames[field] = strings.TrimSpace(v)
+					if i >= columnCount {
+						columnCount = i + 1
+					}
 				}
 			}
 
-			if headerRows == 2 {
-				for k, v := range columnMappings {
-					if v == fieldRequired {
-						return nil, &csv.ParseError{
+			if headerRows == c.profile.HeaderRows {
+				for _, field := range domain.RequiredImportFields {
+					if columnMappings[field] == fieldRequired {
+						return nil, summary, &csv.ParseError{
 							StartLine: 0,
 							Line:      0,
 							Column:    0,
-							Err:       errors.New("column " + k + " not found"),
+							Err:       errors.New("column " + c.getColumnName(field) + " not found"),
 						}
 					}
 				}
 			}
 			continue
 		}
+		summary.Rows++
 
 		// test whether there is a complete empty line
 		// if so, just skip it
@@ -146,37 +232,45 @@ func (c *CsvParser) Parse(reader io.Reader) ([]ImportCenterResult, error) {
 			}
 		}
 		if emptyLine {
+			summary.Empty++
 			continue
 		}
 
+		// rows may be shorter than the header, if the last cells are empty
+		for len(entry) < columnCount {
+			entry = append(entry, "")
+		}
+
 		logrus.WithFields(logrus.Fields{
 			"entry": entry,
 		}).Debug("Importing center")
-		center := c.parseCsvRow(entry, columnMappings)
+		center := c.parseCsvRow(entry, columnMappings, columnNames)
+		center.Line = reader.Line()
 
 		if err := validate.Struct(center.Center); err != nil {
 			if validationErr, ok := err.(validator.ValidationErrors); ok {
 				for _, fieldErr := range validationErr {
 					center.Errors = append(center.Errors, fmt.Sprintf("'%s' failed for '%s'",
-						fieldErr.Field(), fieldErr.Tag()))
+						c.getValidationColumnName(fieldErr.StructField(), columnNames), fieldErr.Tag()))
 				}
 			} else {
-				return nil, err
+				return nil, summary, err
 			}
 		}
 
 		result = append(result, center)
 	}
 
-	return result, nil
+	summary.CountResults(result)
+	return result, summary, nil
 }
 
-func (c *CsvParser) parseCsvRow(entry []string, columnMappings map[string]int) ImportCenterResult {
+func (c *CsvParser) parseCsvRow(entry []string, columnMappings map[string]int, columnNames map[string]string) ImportCenterResult {
 	result := ImportCenterResult{}
 	var err error
 
 	var userReference *string
-	if index, hasColumn := columnMappings[userReferenceIndex]; hasColumn && index > fieldNotFound {
+	if index, hasColumn := columnMappings[domain.ImportFieldUserReference]; hasColumn && index > fieldNotFound {
 		if ref := strings.TrimSpace(entry[index]); ref != "" {
 			userReference = &ref
 		}
@@ -188,105 +282,113 @@ func (c *CsvParser) parseCsvRow(entry []string, columnMappings map[string]int) I
 	}
 
 	var openingHours []string
-	if index, hasColumn := columnMappings[openingHoursIndex]; hasColumn && index > fieldNotFound {
+	if index, hasColumn := columnMappings[domain.ImportFieldOpeningHours]; hasColumn && index > fieldNotFound {
 		openingHours = c.parseOpeningHours(strings.TrimSpace(entry[index]))
+		_, warnings := domain.ParseOpeningHours(openingHours)
+		for _, warning := range warnings {
+			result.Warnings = append(result.Warnings, c.columnMessage(columnNames, domain.ImportFieldOpeningHours, warning))
+		}
 	}
 
 	var appointment *domain.AppointmentType
-	if index, hasColumn := columnMappings[appointmentIndex]; hasColumn && index > fieldNotFound {
+	if index, hasColumn := columnMappings[domain.ImportFieldAppointment]; hasColumn && index > fieldNotFound {
 		appointment, err = c.parseAppointmentType(entry[index])
 		if err != nil {
-			result.Warnings = append(result.Warnings, err.Error())
+			result.Warnings = append(result.Warnings, c.columnMessage(columnNames, domain.ImportFieldAppointment, err.Error()))
 		}
 	}
 
 	var testKinds domain.TestKinds
-	if index, hasColumn := columnMappings[testKindsIndex]; hasColumn && index > fieldNotFound {
+	if index, hasColumn := columnMappings[domain.ImportFieldTestKinds]; hasColumn && index > fieldNotFound {
 		testKinds, err = c.parseTestKinds(entry[index])
 		if err != nil {
-			result.Warnings = append(result.Warnings, err.Error())
+			result.Warnings = append(result.Warnings, c.columnMessage(columnNames, domain.ImportFieldTestKinds, err.Error()))
 		} else if len(testKinds) == 0 {
-			result.Warnings = append(result.Warnings, "no valid testkinds found")
+			result.Warnings = append(result.Warnings, c.columnMessage(columnNames, domain.ImportFieldTestKinds, "no valid testkinds found"))
 		}
 	}
 
 	var website *string
-	if index, hasColumn := columnMappings[websiteIndex]; hasColumn && index > fieldNotFound {
+	if index, hasColumn := columnMappings[domain.ImportFieldWebsite]; hasColumn && index > fieldNotFound {
 		if entry := strings.TrimSpace(entry[index]); entry != "" && strings.ToLower(entry) != "null" {
 			website = &entry
 		}
 	}
 
 	var email *string
-	if index, hasColumn := columnMappings[emailIndex]; hasColumn && index > fieldNotFound {
+	if index, hasColumn := columnMappings[domain.ImportFieldEmail]; hasColumn && index > fieldNotFound {
 		if entry := strings.TrimSpace(entry[index]); entry != "" && strings.ToLower(entry) != "null" {
 			email = &entry
 		}
 	}
 
 	dcc := false
-	if index, hasColumn := columnMappings[dccIndex]; hasColumn && index > fieldNotFound {
-		dcc = strings.ToLower(strings.TrimSpace(entry[index])) == "ja"
+	if index, hasColumn := columnMappings[domain.ImportFieldDCC]; hasColumn && index > fieldNotFound {
+		dcc = c.parseBool(entry[index])
 	}
 
 	var note *string
-	if index, hasColumn := columnMappings[noteIndex]; hasColumn && index > fieldNotFound {
+	if index, hasColumn := columnMappings[domain.ImportFieldAddressNote]; hasColumn && index > fieldNotFound {
 		if entry := strings.TrimSpace(entry[index]); entry != "" {
 			note = &entry
 		}
 	}
 
 	var enterDate *time.Time
-	if index, hasColumn := columnMappings[enterDateIndex]; hasColumn && index > fieldNotFound {
+	if index, hasColumn := columnMappings[domain.ImportFieldEnterDate]; hasColumn && index > fieldNotFound {
 		if dateEntry := strings.TrimSpace(entry[index]); dateEntry != "" {
-			if date, err := time.Parse("_2.1.2006", dateEntry); err == nil {
+			if date, err := c.parseDate(dateEntry); err == nil {
 				enterDate = &date
 			} else {
-				result.Errors = append(result.Errors, "invalid date: "+dateEntry)
+				result.Errors = append(result.Errors, c.columnMessage(columnNames, domain.ImportFieldEnterDate, "invalid date: "+dateEntry))
 			}
 		}
 	}
 
 	var leaveDate *time.Time
-	if index, hasColumn := columnMappings[leaveDateIndex]; hasColumn && index > fieldNotFound {
+	if index, hasColumn := columnMappings[domain.ImportFieldLeaveDate]; hasColumn && index > fieldNotFound {
 		if dateEntry := strings.TrimSpace(entry[index]); dateEntry != "" {
-			if date, err := time.Parse("_2.1.2006", dateEntry); err == nil {
+			if date, err := c.parseDate(dateEntry); err == nil {
 				leaveDate = &date
 			} else {
-				result.Errors = append(result.Errors, "invalid date: "+dateEntry)
+				result.Errors = append(result.Errors, c.columnMessage(columnNames, domain.ImportFieldLeaveDate, "invalid date: "+dateEntry))
 			}
 		}
 	}
 
 	var visible = true
-	if index, hasColumn := columnMappings[visibleIndex]; hasColumn && index > fieldNotFound {
-		visible = strings.ToLower(strings.TrimSpace(entry[index])) == "ja"
+	if index, hasColumn := columnMappings[domain.ImportFieldVisible]; hasColumn && index > fieldNotFound {
+		visible = c.parseBool(entry[index])
 	}
 
 	var operatorName *string
-	if index, hasColumn := columnMappings[operatorNameIndex]; hasColumn && index > fieldNotFound {
+	if index, hasColumn := columnMappings[domain.ImportFieldOperatorName]; hasColumn && index > fieldNotFound {
 		if entry := strings.TrimSpace(entry[index]); entry != "" {
 			operatorName = &entry
 		}
 	}
 
 	var labId *string
-	if index, hasColumn := columnMappings[labIdIndex]; hasColumn && index > fieldNotFound {
+	if index, hasColumn := columnMappings[domain.ImportFieldLabId]; hasColumn && index > fieldNotFound {
 		if entry := strings.TrimSpace(entry[index]); entry != "" {
 			labId = &entry
 		}
 	}
 
 	var latitude, longitude float64
-	if index, hasColumn := columnMappings[latitudeIndex]; hasColumn && index > fieldNotFound {
+	if index, hasColumn := columnMappings[domain.ImportFieldLatitude]; hasColumn && index > fieldNotFound {
 		if entry := strings.TrimSpace(entry[index]); entry != "" {
-			latitude, _ = strconv.ParseFloat(entry, 64)
+			if latitude, err = strconv.ParseFloat(entry, 64); err != nil {
+				result.Warnings = append(result.Warnings, c.columnMessage(columnNames, domain.ImportFieldLatitude, "invalid number: "+entry))
+			}
 		}
 	}
 
-	if index, hasColumn := columnMappings[longitudeIndex]; hasColumn && index > fieldNotFound {
+	if index, hasColumn := columnMappings[domain.ImportFieldLongitude]; hasColumn && index > fieldNotFound {
 		if entry := strings.TrimSpace(entry[index]); entry != "" {
-			longitude, _ = strconv.ParseFloat(entry, 64)
+			if longitude, err = strconv.ParseFloat(entry, 64); err != nil {
+				result.Warnings = append(result.Warnings, c.columnMessage(columnNames, domain.ImportFieldLongitude, "invalid number: "+entry))
+			}
 		}
 	}
 
@@ -297,7 +399,7 @@ func (c *CsvParser) parseCsvRow(entry []string, columnMappings map[string]int) I
 
 	result.Center = domain.Center{
 		UserReference: userReference,
-		Name:          strings.TrimSpace(entry[columnMappings[nameIndex]]),
+		Name:          strings.TrimSpace(entry[columnMappings[domain.ImportFieldName]]),
 		Email:         email,
 		Website:       website,
 		Coordinates: domain.Coordinates{
@@ -321,6 +423,58 @@ func (c *CsvParser) parseCsvRow(entry []string, columnMappings map[string]int) I
 	return result
 }
 
+// getColumnName returns the first column name of the field, used for error messages
+func (c *CsvParser) getColumnName(field string) string {
+	if names := c.profile.Columns[field]; len(names) > 0 {
+		return names[0]
+	}
+	return field
+}
+
+// getFileColumnName returns the column name of the field like it is used in the file
+func (c *CsvParser) getFileColumnName(columnNames map[string]string, field string) string {
+	if name, found := columnNames[field]; found {
+		return name
+	}
+	return c.getColumnName(field)
+}
+
+// getValidationColumnName returns the column name of a validated field of the center
+func (c *CsvParser) getValidationColumnName(structField string, columnNames map[string]string) string {
+	if field, found := validatedImportFields[structField]; found {
+		return c.getFileColumnName(columnNames, field)
+	}
+	return structField
+}
+
+// columnMessage prefixes the warning or error with the column name of the field
+func (c *CsvParser) columnMessage(columnNames map[string]string, field string, message string) string {
+	return c.getFileColumnName(columnNames, field) + ": " + message
+}
+
+// parseBool reports if the value is one of the true values of the profile
+func (c *CsvParser) parseBool(value string) bool {
+	value = strings.TrimSpace(value)
+	for _, trueValue := range c.profile.TrueValues {
+		if strings.EqualFold(value, trueValue) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseDate parses the value using the first matching date format of the profile
+func (c *CsvParser) parseDate(value string) (time.Time, error) {
+	var err error
+	for _, format := range c.profile.DateFormats {
+		var date time.Time
+		if date, err = time.Parse(format, value); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, err
+}
+
 func (*CsvParser) parseOpeningHours(entry string) []string {
 	if entry == "" {
 		return nil
@@ -337,15 +491,15 @@ func (*CsvParser) parseOpeningHours(entry string) []string {
 }
 
 func (*CsvParser) parseAddress(entry []string, columnMappings map[string]int) (string, []string) {
-	address := strings.TrimSpace(entry[columnMappings[streetIndex]])
+	address := strings.TrimSpace(entry[columnMappings[domain.ImportFieldStreet]])
 
 	houseNumber := ""
-	if index, hasColumn := columnMappings[houseNumberIndex]; hasColumn && index > fieldNotFound {
+	if index, hasColumn := columnMappings[domain.ImportFieldHouseNumber]; hasColumn && index > fieldNotFound {
 		houseNumber = strings.TrimSpace(entry[index])
 	}
 
 	postalCode := ""
-	if index, hasColumn := columnMappings[postalCodeIndex]; hasColumn && index > fieldNotFound {
+	if index, hasColumn := columnMappings[domain.ImportFieldZip]; hasColumn && index > fieldNotFound {
 		postalCode = strings.TrimSpace(entry[index])
 	}
 
@@ -354,7 +508,7 @@ func (*CsvParser) parseAddress(entry []string, columnMappings map[string]int) (s
 	}
 
 	city := ""
-	if index, hasColumn := columnMappings[cityIndex]; hasColumn && index > fieldNotFound {
+	if index, hasColumn := columnMappings[domain.ImportFieldCity]; hasColumn && index > fieldNotFound {
 		city = strings.TrimSpace(entry[index])
 	}
 	if houseNumber != "" {
@@ -388,6 +542,11 @@ func (*CsvParser) parseAppointmentType(value string) (*domain.AppointmentType, e
 		tmp := domain.AppointmentRequired
 		return &tmp, nil
 	}
+
+	// english values, like the appointment types of the api
+	if appointment, ok := domain.ParseAppointmentType(strings.ReplaceAll(value, " ", "")); ok {
+		return &appointment, nil
+	}
 	return nil, errors.New("invalid appointment type")
 }
 
@@ -403,6 +562,8 @@ func (*CsvParser) parseTestKinds(value string) (domain.TestKinds, error) {
 			kinds = append(kinds, domain.TestKindPCR)
 		} else if strings.Index(element, "impfung") > -1 {
 			kinds = append(kinds, domain.TestKindVaccination)
+		} else if kind, ok := domain.ParseTestKind(element); ok {
+			kinds = append(kinds, kind)
 		} else {
 			err = errors.New("invalid testkind: " + element)
 		}
@@ -0,0 +1,57 @@
+/*
+ *   Corona-Warn-App / cwa-map-backend
+ *
+ *   (C) 2020, T-Systems International GmbH
+ *
+ *   Deutsche Telekom AG and all other contributors /
+ *   copyright owners license this file to you under the Apache
+ *   License, Version 2.0 (the "License"); you may not use this
+ *   file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing,
+ *   software distributed under the License is distributed on an
+ *   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *   KIND, either express or implied.  See the License for the
+ *   specific language governing permissions and limitations
+ *   under the License.
+ */
+
+package services
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/encoding/charmap"
+	"testing"
+)
+
+func TestParseEncodings(t *testing.T) {
+	content := "Name;Straße;PLZ;Ort;E-Mail;Eintrittsdatum;DCC\nTeststelle Süd;Marienplatz 1;80331;München;;;\n"
+	windows1252, err := charmap.Windows1252.NewEncoder().String(content)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"utf-8", content},
+		{"utf-8 with byte order mark", utf8BOM + content},
+		{"windows-1252", windows1252},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			results, summary, err := NewCsvParser(spreadsheetTestProfile).Parse(bytes.NewReader([]byte(test.data)))
+			require.NoError(t, err)
+			assert.Equal(t, ImportSummary{Rows: 1, Valid: 1}, summary)
+			require.Len(t, results, 1)
+			assert.Empty(t, results[0].Errors)
+			assert.Equal(t, "Teststelle Süd", results[0].Center.Name)
+			assert.Equal(t, "Marienplatz 1, 80331 München", results[0].Center.Address)
+		})
+	}
+}
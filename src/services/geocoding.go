@@ -0,0 +1,171 @@
+/*
+ *   Corona-Warn-App / cwa-map-backend
+ *
+ *   (C) 2020, T-Systems International GmbH
+ *
+ *   Deutsche Telekom AG and all other contributors /
+ *   copyright owners license this file to you under the Apache
+ *   License, Version 2.0 (the "License"); you may not use this
+ *   file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing,
+ *   software distributed under the License is distributed on an
+ *   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *   KIND, either express or implied.  See the License for the
+ *   specific language governing permissions and limitations
+ *   under the License.
+ */
+
+package services
+
+import (
+	"com.t-systems-mms.cwa/domain"
+	"com.t-systems-mms.cwa/repositories"
+	"context"
+	"errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+	"gorm.io/gorm"
+	"math"
+	"time"
+)
+
+const (
+	// geocodingTimeout is the maximum duration of a single geocoding
+	geocodingTimeout = 30 * time.Second
+
+	// geocodingJobLease is the time a claimed job is locked for other workers
+	geocodingJobLease = 5 * time.Minute
+
+	geocodingMinBackoff = 30 * time.Second
+	geocodingMaxBackoff = time.Hour
+)
+
+var (
+	geocodingJobsCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "cwa_map_geocoding_jobs_count",
+		Help: "The total count of processed geocoding jobs",
+	})
+
+	failedGeocodingJobsCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "cwa_map_failed_geocoding_jobs_count",
+		Help: "The total count of geocoding jobs failed after all attempts",
+	})
+)
+
+type GeocodingWorkerConfig struct {
+	// Workers is the count of concurrently processed jobs
+	Workers int
+
+	// QPS is the maximum count of geocoding requests per second
+	QPS int
+
+	// MaxAttempts is the count of attempts before a job is dropped
+	MaxAttempts int
+
+	// PollInterval is the interval (in seconds) in which new jobs are claimed, if there were no jobs
+	PollInterval int
+}
+
+type GeocodingWorker interface {
+	// GeocodingScheduler processes the pending geocoding jobs
+	GeocodingScheduler()
+}
+
+type geocodingWorker struct {
+	config         GeocodingWorkerConfig
+	jobsRepository repositories.GeocodingJobs
+	centersService Centers
+	limiter        *rate.Limiter
+}
+
+func NewGeocodingWorker(config GeocodingWorkerConfig, jobsRepository repositories.GeocodingJobs, centersService Centers) GeocodingWorker {
+	return &geocodingWorker{
+		config:         config,
+		jobsRepository: jobsRepository,
+		centersService: centersService,
+		limiter:        rate.NewLimiter(rate.Limit(config.QPS), 1),
+	}
+}
+
+func (w *geocodingWorker) GeocodingScheduler() {
+	logrus.WithFields(logrus.Fields{
+		"workers": w.config.Workers,
+		"qps":     w.config.QPS,
+	}).Info("Geocoding scheduler started")
+
+	jobs := make(chan domain.GeocodingJob)
+	for i := 0; i < w.config.Workers; i++ {
+		go w.work(jobs)
+	}
+
+	for {
+		claimed, err := w.jobsRepository.Claim(context.Background(), w.config.Workers, geocodingJobLease)
+		if err != nil {
+			logrus.WithError(err).Error("Error claiming geocoding jobs")
+		}
+
+		if len(claimed) == 0 {
+			time.Sleep(time.Duration(w.config.PollInterval) * time.Second)
+			continue
+		}
+
+		for _, job := range claimed {
+			jobs <- job
+		}
+	}
+}
+
+func (w *geocodingWorker) work(jobs <-chan domain.GeocodingJob) {
+	for job := range jobs {
+		if err := w.limiter.Wait(context.Background()); err != nil {
+			logrus.WithError(err).Error("Error waiting for geocoding rate limit")
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), geocodingTimeout)
+		err := w.centersService.Geocode(ctx, job.CenterUUID)
+		cancel()
+		w.finish(job, err)
+	}
+}
+
+// finish completes the job or schedules the next attempt using an exponential backoff
+func (w *geocodingWorker) finish(job domain.GeocodingJob, err error) {
+	geocodingJobsCounter.Inc()
+	if err == nil || errors.Is(err, gorm.ErrRecordNotFound) {
+		if err := w.jobsRepository.Complete(context.Background(), job); err != nil {
+			logrus.WithError(err).WithField("center", job.CenterUUID).Error("Error completing geocoding job")
+		}
+		return
+	}
+
+	logger := logrus.WithFields(logrus.Fields{
+		"center":   job.CenterUUID,
+		"attempts": job.Attempts,
+	}).WithError(err)
+
+	if job.Attempts >= w.config.MaxAttempts {
+		failedGeocodingJobsCounter.Inc()
+		logger.Error("Geocoding failed, giving up")
+		if err := w.centersService.GeocodingFailed(context.Background(), job.CenterUUID, err); err != nil {
+			logrus.WithError(err).WithField("center", job.CenterUUID).Error("Error updating geocoding status")
+		}
+		if err := w.jobsRepository.Complete(context.Background(), job); err != nil {
+			logrus.WithError(err).WithField("center", job.CenterUUID).Error("Error completing geocoding job")
+		}
+		return
+	}
+
+	backoff := time.Duration(math.Min(
+		float64(geocodingMinBackoff)*math.Pow(2, float64(job.Attempts-1)),
+		float64(geocodingMaxBackoff)))
+	logger.WithField("backoff", backoff).Warn("Geocoding failed, retrying later")
+	if err := w.jobsRepository.Retry(context.Background(), job, time.Now().Add(backoff), err.Error()); err != nil {
+		logrus.WithError(err).WithField("center", job.CenterUUID).Error("Error rescheduling geocoding job")
+	}
+}
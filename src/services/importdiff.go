@@ -0,0 +1,241 @@
+/*
+ *   Corona-Warn-App / cwa-map-backend
+ *
+ *   (C) 2020, T-Systems International GmbH
+ *
+ *   Deutsche Telekom AG and all other contributors /
+ *   copyright owners license this file to you under the Apache
+ *   License, Version 2.0 (the "License"); you may not use this
+ *   file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing,
+ *   software distributed under the License is distributed on an
+ *   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *   KIND, either express or implied.  See the License for the
+ *   specific language governing permissions and limitations
+ *   under the License.
+ */
+
+package services
+
+import (
+	"com.t-systems-mms.cwa/core/security"
+	"com.t-systems-mms.cwa/core/util"
+	"com.t-systems-mms.cwa/domain"
+	"com.t-systems-mms.cwa/external/geocoding"
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// ImportDiff describes the changes an import would apply to the centers of the operator
+type ImportDiff struct {
+	Created   []ImportDiffEntry
+	Updated   []ImportDiffEntry
+	Unchanged []ImportDiffEntry
+	Deleted   []domain.Center
+}
+
+// ImportDiffEntry is an imported center and the existing center it has been matched to, if any.
+type ImportDiffEntry struct {
+	Center   domain.Center
+	Existing *domain.Center
+	Changes  []FieldChange
+
+	// Probable reports that the existing center has only been matched by its address.
+	// Unless all existing centers are deleted, it is not replaced by the import, but is probably a duplicate.
+	Probable bool
+}
+
+// FieldChange is the change of a single attribute of a center
+type FieldChange struct {
+	Field    string
+	OldValue interface{}
+	NewValue interface{}
+}
+
+// importDiffFields are the attributes of a center, which are compared for the diff of an import
+var importDiffFields = []struct {
+	name  string
+	value func(center *domain.Center) interface{}
+}{
+	{"name", func(c *domain.Center) interface{} { return c.Name }},
+	{"operatorName", func(c *domain.Center) interface{} { return derefString(c.OperatorName) }},
+	{"labId", func(c *domain.Center) interface{} { return derefString(c.LabId) }},
+	{"email", func(c *domain.Center) interface{} { return derefString(c.Email) }},
+	{"website", func(c *domain.Center) interface{} { return derefString(c.Website) }},
+	{"address", func(c *domain.Center) interface{} { return c.Address }},
+	{"addressNote", func(c *domain.Center) interface{} { return derefString(c.AddressNote) }},
+	{"openingHours", func(c *domain.Center) interface{} { return []string(c.OpeningHours) }},
+	{"appointment", func(c *domain.Center) interface{} { return derefString((*string)(c.Appointment)) }},
+	{"testKinds", func(c *domain.Center) interface{} { return []string(c.TestKinds) }},
+	{"dcc", func(c *domain.Center) interface{} { return c.DCC != nil && *c.DCC }},
+	{"enterDate", func(c *domain.Center) interface{} { return formatDate(c.EnterDate) }},
+	{"leaveDate", func(c *domain.Center) interface{} { return formatDate(c.LeaveDate) }},
+	{"visible", func(c *domain.Center) interface{} { return c.Visible == nil || *c.Visible }},
+	{"coordinates", func(c *domain.Center) interface{} {
+		if !c.Coordinates.Fixed {
+			return nil
+		}
+		return fmt.Sprintf("%f,%f", c.Coordinates.Latitude, c.Coordinates.Longitude)
+	}},
+}
+
+// DiffImport compares the centers of an import with the existing centers of the current operator without saving them.
+// See diffImportedCenters for the matching of the centers.
+func (s *centersService) DiffImport(ctx context.Context, centers []domain.Center, deleteAll bool) (ImportDiff, error) {
+	for _, center := range centers {
+		if err := s.validate.Struct(center); err != nil {
+			return ImportDiff{}, err
+		}
+	}
+
+	operator, err := s.operatorsService.GetCurrentOperator(ctx)
+	if err != nil {
+		return ImportDiff{}, err
+	}
+
+	existing, err := s.centersRepository.FindAllByOperator(ctx, operator.UUID)
+	if err != nil {
+		return ImportDiff{}, err
+	}
+
+	imported := make([]domain.Center, len(centers))
+	hasDCCRole := security.HasRole(ctx, security.RoleDCC)
+	for i, center := range centers {
+		if !hasDCCRole {
+			// the dcc flag is reset by Save
+			tmp := false
+			center.DCC = &tmp
+		}
+		imported[i] = center
+	}
+	return diffImportedCenters(existing, imported, deleteAll)
+}
+
+// diffImportedCenters matches the imported centers to the existing centers.
+// Centers are matched by their user reference, like the import replaces them. The remaining centers are matched
+// to the remaining existing centers with the same address as probable match, which is reported as created.
+// If all existing centers are deleted, the matched centers are reported as updated or unchanged, although they are
+// saved with a new UUID, and only the existing centers without a match are reported as deleted.
+func diffImportedCenters(existing, imported []domain.Center, deleteAll bool) (ImportDiff, error) {
+	byReference := make(map[string]*domain.Center)
+	byAddress := make(map[string][]*domain.Center)
+	for i := range existing {
+		center := &existing[i]
+		if util.IsNotNilOrEmpty(center.UserReference) {
+			byReference[*center.UserReference] = center
+		}
+		address := geocoding.NormalizeAddress(center.Address)
+		byAddress[address] = append(byAddress[address], center)
+	}
+
+	entries := make([]ImportDiffEntry, len(imported))
+	matched := make(map[string]bool)
+	for i, center := range imported {
+		entries[i].Center = center
+		if util.IsNilOrEmpty(center.UserReference) {
+			continue
+		}
+
+		if match := byReference[*center.UserReference]; match != nil {
+			if !deleteAll && util.IsNotNilOrEmpty(&center.UUID) && match.UUID != center.UUID {
+				return ImportDiff{}, ErrDuplicateUserReference
+			}
+			entries[i].Existing = match
+			matched[match.UUID] = true
+		}
+	}
+
+	for i := range entries {
+		entry := &entries[i]
+		if entry.Existing != nil {
+			continue
+		}
+
+		for _, match := range byAddress[geocoding.NormalizeAddress(entry.Center.Address)] {
+			if !matched[match.UUID] {
+				entry.Existing = match
+				entry.Probable = true
+				matched[match.UUID] = true
+				break
+			}
+		}
+	}
+
+	diff := ImportDiff{
+		Created:   make([]ImportDiffEntry, 0),
+		Updated:   make([]ImportDiffEntry, 0),
+		Unchanged: make([]ImportDiffEntry, 0),
+		Deleted:   make([]domain.Center, 0),
+	}
+	for _, entry := range entries {
+		if entry.Existing != nil {
+			entry.Changes = diffCenters(entry.Existing, &entry.Center)
+		}
+
+		if entry.Existing == nil || (entry.Probable && !deleteAll) {
+			diff.Created = append(diff.Created, entry)
+		} else if len(entry.Changes) > 0 {
+			diff.Updated = append(diff.Updated, entry)
+		} else {
+			diff.Unchanged = append(diff.Unchanged, entry)
+		}
+	}
+
+	if deleteAll {
+		for _, center := range existing {
+			if !matched[center.UUID] {
+				diff.Deleted = append(diff.Deleted, center)
+			}
+		}
+	}
+	return diff, nil
+}
+
+// diffCenters returns the changed attributes between the existing and the imported center
+func diffCenters(existing, imported *domain.Center) []FieldChange {
+	changes := make([]FieldChange, 0)
+	for _, field := range importDiffFields {
+		oldValue, newValue := field.value(existing), field.value(imported)
+		if !isEmptyValue(oldValue) || !isEmptyValue(newValue) {
+			if !reflect.DeepEqual(oldValue, newValue) {
+				changes = append(changes, FieldChange{Field: field.name, OldValue: oldValue, NewValue: newValue})
+			}
+		}
+	}
+	return changes
+}
+
+// isEmptyValue reports if the value is nil, an empty string or an empty list,
+// so nil and empty values are not reported as changes
+func isEmptyValue(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.String, reflect.Slice:
+		return v.Len() == 0
+	}
+	return false
+}
+
+func derefString(value *string) interface{} {
+	if value == nil {
+		return nil
+	}
+	return *value
+}
+
+func formatDate(value *time.Time) interface{} {
+	if value == nil {
+		return nil
+	}
+	return value.Format("2006-01-02")
+}
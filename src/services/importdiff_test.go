@@ -0,0 +1,100 @@
+/*
+ *   Corona-Warn-App / cwa-map-backend
+ *
+ *   (C) 2020, T-Systems International GmbH
+ *
+ *   Deutsche Telekom AG and all other contributors /
+ *   copyright owners license this file to you under the Apache
+ *   License, Version 2.0 (the "License"); you may not use this
+ *   file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing,
+ *   software distributed under the License is distributed on an
+ *   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *   KIND, either express or implied.  See the License for the
+ *   specific language governing permissions and limitations
+ *   under the License.
+ */
+
+package services
+
+import (
+	"com.t-systems-mms.cwa/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestDiffImportedCenters(t *testing.T) {
+	reference := func(value string) *string {
+		return &value
+	}
+	existing := []domain.Center{
+		{UUID: "referenced", UserReference: reference("1"), Name: "Mitte", Address: "Invalidenstr. 1, 10115 Berlin"},
+		{UUID: "unchanged", UserReference: reference("2"), Name: "Süd", Address: "Marienplatz 1, 80331 München"},
+		{UUID: "addressed", Name: "Nord", Address: "Jungfernstieg 1, 20354 Hamburg"},
+		{UUID: "removed", UserReference: reference("4"), Name: "West", Address: "Domkloster 4, 50667 Köln"},
+	}
+	imported := []domain.Center{
+		{UserReference: reference("1"), Name: "Zentrum Mitte", Address: "Invalidenstr. 1, 10115 Berlin"},
+		{UserReference: reference("2"), Name: "Süd", Address: "Marienplatz 1, 80331 München"},
+		{Name: "Nord", Address: "jungfernstieg 1  20354 Hamburg"},
+		{UserReference: reference("5"), Name: "Ost", Address: "Alexanderplatz 1, 10178 Berlin"},
+	}
+
+	t.Run("keep existing centers", func(t *testing.T) {
+		diff, err := diffImportedCenters(existing, imported, false)
+		require.NoError(t, err)
+
+		require.Len(t, diff.Updated, 1)
+		assert.Equal(t, "referenced", diff.Updated[0].Existing.UUID)
+		assert.Equal(t, []FieldChange{{Field: "name", OldValue: "Mitte", NewValue: "Zentrum Mitte"}}, diff.Updated[0].Changes)
+		assert.False(t, diff.Updated[0].Probable)
+
+		require.Len(t, diff.Unchanged, 1)
+		assert.Equal(t, "unchanged", diff.Unchanged[0].Existing.UUID)
+
+		// the probable match is created by the import
+		require.Len(t, diff.Created, 2)
+		assert.Equal(t, "addressed", diff.Created[0].Existing.UUID)
+		assert.True(t, diff.Created[0].Probable)
+		assert.Equal(t, []FieldChange{{Field: "address",
+			OldValue: "Jungfernstieg 1, 20354 Hamburg", NewValue: "jungfernstieg 1  20354 Hamburg"}}, diff.Created[0].Changes)
+		assert.Nil(t, diff.Created[1].Existing)
+		assert.Empty(t, diff.Deleted)
+	})
+
+	t.Run("delete all existing centers", func(t *testing.T) {
+		diff, err := diffImportedCenters(existing, imported, true)
+		require.NoError(t, err)
+
+		require.Len(t, diff.Updated, 2)
+		assert.Equal(t, "referenced", diff.Updated[0].Existing.UUID)
+		assert.Equal(t, "addressed", diff.Updated[1].Existing.UUID)
+		assert.True(t, diff.Updated[1].Probable)
+		require.Len(t, diff.Unchanged, 1)
+		require.Len(t, diff.Created, 1)
+		assert.Nil(t, diff.Created[0].Existing)
+		require.Len(t, diff.Deleted, 1)
+		assert.Equal(t, "removed", diff.Deleted[0].UUID)
+	})
+
+	t.Run("matched centers are not matched by address again", func(t *testing.T) {
+		duplicate := domain.Center{Name: "Mitte", Address: "Invalidenstr. 1, 10115 Berlin"}
+		diff, err := diffImportedCenters(existing, append([]domain.Center{duplicate}, imported...), false)
+		require.NoError(t, err)
+
+		require.Len(t, diff.Created, 3)
+		assert.Nil(t, diff.Created[0].Existing)
+		assert.Equal(t, "referenced", diff.Updated[0].Existing.UUID)
+	})
+
+	t.Run("duplicate user reference", func(t *testing.T) {
+		center := domain.Center{UUID: "other", UserReference: reference("1"), Address: "Invalidenstr. 1, 10115 Berlin"}
+		_, err := diffImportedCenters(existing, []domain.Center{center}, false)
+		assert.Equal(t, ErrDuplicateUserReference, err)
+	})
+}
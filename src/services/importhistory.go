@@ -0,0 +1,158 @@
+/*
+ *   Corona-Warn-App / cwa-map-backend
+ *
+ *   (C) 2020, T-Systems International GmbH
+ *
+ *   Deutsche Telekom AG and all other contributors /
+ *   copyright owners license this file to you under the Apache
+ *   License, Version 2.0 (the "License"); you may not use this
+ *   file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing,
+ *   software distributed under the License is distributed on an
+ *   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *   KIND, either express or implied.  See the License for the
+ *   specific language governing permissions and limitations
+ *   under the License.
+ */
+
+package services
+
+import (
+	"com.t-systems-mms.cwa/core/security"
+	"com.t-systems-mms.cwa/domain"
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"time"
+)
+
+// newImportRecord creates the import history entry, existing contains the centers of the operator before the import
+func newImportRecord(options ImportOptions, existing []domain.Center, imported []domain.Center) domain.ImportRecord {
+	record := domain.ImportRecord{
+		UUID:           uuid.New().String(),
+		OperatorUUID:   options.OperatorUUID,
+		ImportedBy:     options.User,
+		Imported:       time.Now(),
+		DeleteAll:      options.DeleteAll,
+		Partial:        options.Partial,
+		CreatedCenters: pq.StringArray{},
+		Snapshot:       domain.CenterList{},
+	}
+
+	existingCenters := make(map[string]domain.Center, len(existing))
+	for _, center := range existing {
+		existingCenters[center.UUID] = center
+	}
+
+	// centers may be imported several times, if their user reference is not unique within the import
+	importedCenters := make(map[string]bool, len(imported))
+	for _, center := range imported {
+		if importedCenters[center.UUID] {
+			continue
+		}
+		importedCenters[center.UUID] = true
+
+		if previous, found := existingCenters[center.UUID]; found {
+			record.Updated++
+			record.Snapshot = append(record.Snapshot, previous)
+		} else {
+			record.Created++
+			record.CreatedCenters = append(record.CreatedCenters, center.UUID)
+		}
+	}
+
+	if options.DeleteAll {
+		for _, center := range existing {
+			if !importedCenters[center.UUID] {
+				record.Deleted++
+				record.Snapshot = append(record.Snapshot, center)
+			}
+		}
+	}
+	return record
+}
+
+func (s *centersService) RollbackLastImport(ctx context.Context, operatorUUID string) (domain.ImportRecord, error) {
+	var record domain.ImportRecord
+	err := s.centersRepository.UseTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		record, err = s.importRecords.FindLatestByOperator(ctx, operatorUUID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNothingToRollback
+		} else if err != nil {
+			return err
+		} else if record.RolledBack != nil {
+			return ErrNothingToRollback
+		}
+
+		current, err := s.centersRepository.FindAllByOperator(ctx, operatorUUID)
+		if err != nil {
+			return err
+		}
+
+		created := make(map[string]bool, len(record.CreatedCenters))
+		for _, uuid := range record.CreatedCenters {
+			created[uuid] = true
+		}
+		if err := checkUnmodifiedSinceImport(record, current, created); err != nil {
+			return err
+		}
+
+		for _, center := range current {
+			if created[center.UUID] {
+				if err := s.centersRepository.Delete(ctx, center); err != nil {
+					return err
+				}
+			}
+		}
+
+		for i := range record.Snapshot {
+			if err := s.centersRepository.Restore(ctx, &record.Snapshot[i]); err != nil {
+				return err
+			}
+		}
+
+		rolledBack := time.Now()
+		rolledBackBy := security.GetUsername(ctx)
+		record.RolledBack = &rolledBack
+		record.RolledBackBy = &rolledBackBy
+		return s.importRecords.Save(ctx, &record)
+	})
+	if err != nil {
+		return domain.ImportRecord{}, err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"operator": operatorUUID,
+		"import":   record.UUID,
+		"created":  record.Created,
+		"restored": len(record.Snapshot),
+	}).Info("Rolled back import")
+	return record, nil
+}
+
+// checkUnmodifiedSinceImport returns ErrModifiedSinceImport, if any center created or replaced by the import
+// has been saved again after the import
+func checkUnmodifiedSinceImport(record domain.ImportRecord, current []domain.Center, created map[string]bool) error {
+	imported := make(map[string]bool, len(created)+len(record.Snapshot))
+	for uuid := range created {
+		imported[uuid] = true
+	}
+	for _, center := range record.Snapshot {
+		imported[center.UUID] = true
+	}
+
+	for _, center := range current {
+		if imported[center.UUID] && center.LastUpdate != nil && center.LastUpdate.After(record.Imported) {
+			return ErrModifiedSinceImport
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+/*
+ *   Corona-Warn-App / cwa-map-backend
+ *
+ *   (C) 2020, T-Systems International GmbH
+ *
+ *   Deutsche Telekom AG and all other contributors /
+ *   copyright owners license this file to you under the Apache
+ *   License, Version 2.0 (the "License"); you may not use this
+ *   file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing,
+ *   software distributed under the License is distributed on an
+ *   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *   KIND, either express or implied.  See the License for the
+ *   specific language governing permissions and limitations
+ *   under the License.
+ */
+
+package services
+
+import (
+	"com.t-systems-mms.cwa/domain"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestCheckUnmodifiedSinceImport(t *testing.T) {
+	imported := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	before, after := imported.Add(-time.Minute), imported.Add(time.Minute)
+	record := domain.ImportRecord{
+		Imported: imported,
+		Snapshot: domain.CenterList{{UUID: "updated"}},
+	}
+	created := map[string]bool{"created": true}
+
+	tests := []struct {
+		name     string
+		current  []domain.Center
+		expected error
+	}{
+		{"unmodified", []domain.Center{
+			{UUID: "updated", LastUpdate: &before},
+			{UUID: "created", LastUpdate: &before},
+		}, nil},
+		{"other center modified", []domain.Center{
+			{UUID: "updated", LastUpdate: &before},
+			{UUID: "other", LastUpdate: &after},
+		}, nil},
+		{"updated center modified", []domain.Center{
+			{UUID: "updated", LastUpdate: &after},
+		}, ErrModifiedSinceImport},
+		{"created center modified", []domain.Center{
+			{UUID: "updated", LastUpdate: &before},
+			{UUID: "created", LastUpdate: &after},
+		}, ErrModifiedSinceImport},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, checkUnmodifiedSinceImport(record, test.current, created))
+		})
+	}
+}
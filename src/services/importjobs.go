@@ -0,0 +1,328 @@
+/*
+ *   Corona-Warn-App / cwa-map-backend
+ *
+ *   (C) 2020, T-Systems International GmbH
+ *
+ *   Deutsche Telekom AG and all other contributors /
+ *   copyright owners license this file to you under the Apache
+ *   License, Version 2.0 (the "License"); you may not use this
+ *   file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing,
+ *   software distributed under the License is distributed on an
+ *   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *   KIND, either express or implied.  See the License for the
+ *   specific language governing permissions and limitations
+ *   under the License.
+ */
+
+package services
+
+import (
+	"com.t-systems-mms.cwa/core"
+	"com.t-systems-mms.cwa/core/security"
+	"com.t-systems-mms.cwa/domain"
+	"com.t-systems-mms.cwa/repositories"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// importJobLease is the time a claimed job is locked for other workers
+	importJobLease = 5 * time.Minute
+
+	// importJobLeaseRenewal is the interval in which the lease of a processed job is extended
+	importJobLeaseRenewal = time.Minute
+
+	// importJobCleanupInterval is the interval in which expired jobs are deleted
+	importJobCleanupInterval = time.Hour
+
+	// maxImportJobErrorLength is the maximum length of the error of a failed job
+	maxImportJobErrorLength = 512
+)
+
+var (
+	importJobsCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "cwa_map_import_jobs_count",
+		Help: "The total count of processed import jobs",
+	})
+
+	failedImportJobsCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "cwa_map_failed_import_jobs_count",
+		Help: "The total count of import jobs, which did not import any center",
+	})
+)
+
+type ImportJobsConfig struct {
+	// PollInterval is the interval (in seconds) in which new jobs are claimed, if there were no jobs
+	PollInterval int
+
+	// Retention is the time (in days) finished jobs are kept
+	Retention int
+}
+
+type ImportJobs interface {
+	// Submit creates a job importing the centers for the current operator in the background.
+	// The rejected centers already failed the validation of a partial import, their errors are kept in the job.
+	Submit(ctx context.Context, centers []domain.Center, rejected domain.ImportJobErrors, deleteAll, partial bool) (domain.ImportJob, error)
+
+	// FindByUUID finds the job of the current operator
+	FindByUUID(ctx context.Context, uuid string) (domain.ImportJob, error)
+
+	// FindAll finds the jobs of the current operator, the latest jobs first
+	FindAll(ctx context.Context, page repositories.PageRequest) (repositories.PagedImportJobsResult, error)
+
+	// ImportScheduler processes the pending import jobs
+	ImportScheduler()
+}
+
+type importJobsService struct {
+	config           ImportJobsConfig
+	jobsRepository   repositories.ImportJobs
+	centersService   Centers
+	operatorsService Operators
+}
+
+func NewImportJobsService(config ImportJobsConfig, jobsRepository repositories.ImportJobs, centersService Centers, operatorsService Operators) ImportJobs {
+	return &importJobsService{
+		config:           config,
+		jobsRepository:   jobsRepository,
+		centersService:   centersService,
+		operatorsService: operatorsService,
+	}
+}
+
+func (s *importJobsService) Submit(ctx context.Context, centers []domain.Center, rejected domain.ImportJobErrors, deleteAll, partial bool) (domain.ImportJob, error) {
+	operator, err := s.operatorsService.GetCurrentOperator(ctx)
+	if err != nil {
+		return domain.ImportJob{}, err
+	}
+
+	job := domain.ImportJob{
+		UUID:         uuid.New().String(),
+		OperatorUUID: operator.UUID,
+		Status:       domain.ImportJobPending,
+		DeleteAll:    deleteAll,
+		Partial:      partial,
+		SubmittedBy:  security.GetUsername(ctx),
+		AllowDCC:     security.HasRole(ctx, security.RoleDCC),
+		Centers:      centers,
+		Rejected:     rejected,
+		Total:        len(centers) + len(rejected),
+		Errors:       domain.ImportJobErrors{},
+		Created:      time.Now(),
+	}
+	if err := s.jobsRepository.Save(ctx, &job); err != nil {
+		return domain.ImportJob{}, err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"job":      job.UUID,
+		"operator": operator.UUID,
+		"count":    job.Total,
+	}).Info("Submitted import job")
+	return job, nil
+}
+
+func (s *importJobsService) FindByUUID(ctx context.Context, uuid string) (domain.ImportJob, error) {
+	operator, err := s.operatorsService.GetCurrentOperator(ctx)
+	if err != nil {
+		return domain.ImportJob{}, err
+	}
+	return s.jobsRepository.FindByOperatorAndUUID(ctx, operator.UUID, uuid)
+}
+
+func (s *importJobsService) FindAll(ctx context.Context, page repositories.PageRequest) (repositories.PagedImportJobsResult, error) {
+	operator, err := s.operatorsService.GetCurrentOperator(ctx)
+	if err != nil {
+		return repositories.PagedImportJobsResult{}, err
+	}
+	return s.jobsRepository.FindByOperator(ctx, operator.UUID, page)
+}
+
+func (s *importJobsService) ImportScheduler() {
+	logrus.WithFields(logrus.Fields{
+		"pollInterval": s.config.PollInterval,
+	}).Info("Import scheduler started")
+
+	var lastCleanup time.Time
+	for {
+		if time.Since(lastCleanup) > importJobCleanupInterval {
+			s.deleteExpiredJobs()
+			lastCleanup = time.Now()
+		}
+
+		job, err := s.jobsRepository.Claim(context.Background(), importJobLease)
+		if err != nil {
+			logrus.WithError(err).Error("Error claiming import job")
+		}
+
+		if job == nil {
+			time.Sleep(time.Duration(s.config.PollInterval) * time.Second)
+			continue
+		}
+		s.process(job)
+	}
+}
+
+// process imports the centers of the claimed job. Jobs claimed again after their lease expired are started over,
+// because the transaction of the previous attempt has been rolled back.
+func (s *importJobsService) process(job *domain.ImportJob) {
+	ctx := context.Background()
+	logger := logrus.WithFields(logrus.Fields{
+		"job":      job.UUID,
+		"operator": job.OperatorUUID,
+	})
+	logger.WithField("count", job.Total).Info("Processing import job")
+
+	countResults(job, nil)
+	stopRenewal := s.renewLease(logger, job.UUID)
+	results, err := s.centersService.ImportCentersWithOptions(ctx, job.Centers, ImportOptions{
+		OperatorUUID: job.OperatorUUID,
+		User:         job.SubmittedBy,
+		AllowDCC:     job.AllowDCC,
+		DeleteAll:    job.DeleteAll,
+		Partial:      job.Partial,
+		Progress: func(results []ImportItemResult) {
+			countResults(job, results)
+			if err := s.jobsRepository.UpdateProgress(ctx, job, importJobLease); err != nil {
+				logger.WithError(err).Error("Error updating progress of import job")
+			}
+		},
+	})
+	stopRenewal()
+
+	finished := time.Now()
+	job.Finished = &finished
+	job.Status = domain.ImportJobCompleted
+	if err != nil {
+		// nothing has been saved
+		job.Succeeded = 0
+		job.Error = importJobError(ImportErrorMessage(err))
+	} else {
+		countResults(job, results)
+		if job.Total > 0 && job.Succeeded == 0 {
+			job.Error = importJobError("no center imported")
+		}
+	}
+
+	importJobsCounter.Inc()
+	if job.Error != nil {
+		failedImportJobsCounter.Inc()
+		job.Status = domain.ImportJobFailed
+		logger.WithField("error", *job.Error).Warn("Import job failed")
+	} else {
+		logger.WithFields(logrus.Fields{
+			"succeeded": job.Succeeded,
+			"failed":    job.Failed,
+		}).Info("Import job completed")
+	}
+
+	if err := s.jobsRepository.UpdateProgress(ctx, job, 0); err != nil {
+		logger.WithError(err).Error("Error finishing import job")
+	}
+}
+
+// renewLease extends the lease of the job periodically until the returned function is called,
+// so the job is not claimed by another worker while it is processed, independent of its progress.
+func (s *importJobsService) renewLease(logger *logrus.Entry, uuid string) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(importJobLeaseRenewal)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := s.jobsRepository.RenewLease(context.Background(), uuid, importJobLease); err != nil {
+					logger.WithError(err).Error("Error renewing lease of import job")
+				}
+			}
+		}
+	}()
+	return func() {
+		close(done)
+	}
+}
+
+func (s *importJobsService) deleteExpiredJobs() {
+	count, err := s.jobsRepository.DeleteFinishedBefore(context.Background(),
+		time.Now().Add(-time.Duration(s.config.Retention)*24*time.Hour))
+	if err != nil {
+		logrus.WithError(err).Error("Error deleting expired import jobs")
+	} else if count > 0 {
+		logrus.WithField("count", count).Info("Deleted expired import jobs")
+	}
+}
+
+// countResults updates the counters and errors of the job using the results of the processed centers.
+// The rejected centers are counted as failed, the errors use the indices of the submitted centers.
+func countResults(job *domain.ImportJob, results []ImportItemResult) {
+	job.Processed, job.Succeeded, job.Failed = len(job.Rejected)+len(results), 0, len(job.Rejected)
+	job.Errors = append(domain.ImportJobErrors{}, job.Rejected...)
+	job.Error = nil
+
+	rejected := make(map[int]bool)
+	for _, rejectedCenter := range job.Rejected {
+		rejected[rejectedCenter.Index] = true
+	}
+
+	index := 0
+	for _, result := range results {
+		for rejected[index] {
+			index++
+		}
+
+		if result.Error == nil {
+			job.Succeeded++
+		} else {
+			job.Failed++
+			job.Errors = append(job.Errors, domain.ImportJobError{Index: index, Error: ImportErrorMessage(result.Error)})
+		}
+		index++
+	}
+
+	sort.Slice(job.Errors, func(a, b int) bool {
+		return job.Errors[a].Index < job.Errors[b].Index
+	})
+}
+
+func importJobError(message string) *string {
+	if len(message) > maxImportJobErrorLength {
+		message = message[:maxImportJobErrorLength]
+	}
+	return &message
+}
+
+// ImportErrorMessage returns the message of an error of an imported center, which can be shown to the operator.
+// The messages of unexpected errors are replaced by a generic message.
+func ImportErrorMessage(err error) string {
+	var validationErrors validator.ValidationErrors
+	var applicationError core.ApplicationError
+	if errors.As(err, &validationErrors) {
+		fields := make([]string, len(validationErrors))
+		for i, fieldErr := range validationErrors {
+			fields[i] = fmt.Sprintf("'%s' failed for '%s'", fieldErr.Field(), fieldErr.Tag())
+		}
+		return strings.Join(fields, ", ")
+	} else if errors.As(err, &applicationError) {
+		return applicationError.Error()
+	} else if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "record not found"
+	}
+	return "internal server error"
+}
@@ -0,0 +1,61 @@
+/*
+ *   Corona-Warn-App / cwa-map-backend
+ *
+ *   (C) 2020, T-Systems International GmbH
+ *
+ *   Deutsche Telekom AG and all other contributors /
+ *   copyright owners license this file to you under the Apache
+ *   License, Version 2.0 (the "License"); you may not use this
+ *   file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing,
+ *   software distributed under the License is distributed on an
+ *   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *   KIND, either express or implied.  See the License for the
+ *   specific language governing permissions and limitations
+ *   under the License.
+ */
+
+package services
+
+import (
+	"com.t-systems-mms.cwa/domain"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCountResultsWithRejectedCenters(t *testing.T) {
+	job := &domain.ImportJob{
+		Total: 5,
+		Rejected: domain.ImportJobErrors{
+			{Index: 0, Error: "'name' failed for 'required'"},
+			{Index: 3, Error: "'email' failed for 'email'"},
+		},
+	}
+
+	// the results of the submitted centers 1, 2 and 4
+	countResults(job, []ImportItemResult{{}, {Error: errors.New("unexpected")}, {}})
+
+	assert.Equal(t, 5, job.Processed)
+	assert.Equal(t, 2, job.Succeeded)
+	assert.Equal(t, 3, job.Failed)
+	assert.Equal(t, domain.ImportJobErrors{
+		{Index: 0, Error: "'name' failed for 'required'"},
+		{Index: 2, Error: "internal server error"},
+		{Index: 3, Error: "'email' failed for 'email'"},
+	}, job.Errors)
+}
+
+func TestCountResultsWithoutRejectedCenters(t *testing.T) {
+	job := &domain.ImportJob{Total: 2}
+	countResults(job, []ImportItemResult{{Error: ErrDuplicateUserReference}})
+
+	assert.Equal(t, 1, job.Processed)
+	assert.Equal(t, 0, job.Succeeded)
+	assert.Equal(t, 1, job.Failed)
+	assert.Equal(t, domain.ImportJobErrors{{Index: 0, Error: ErrDuplicateUserReference.Error()}}, job.Errors)
+}
@@ -0,0 +1,108 @@
+/*
+ *   Corona-Warn-App / cwa-map-backend
+ *
+ *   (C) 2020, T-Systems International GmbH
+ *
+ *   Deutsche Telekom AG and all other contributors /
+ *   copyright owners license this file to you under the Apache
+ *   License, Version 2.0 (the "License"); you may not use this
+ *   file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing,
+ *   software distributed under the License is distributed on an
+ *   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *   KIND, either express or implied.  See the License for the
+ *   specific language governing permissions and limitations
+ *   under the License.
+ */
+
+package services
+
+import (
+	"com.t-systems-mms.cwa/core"
+	"com.t-systems-mms.cwa/core/util"
+	"com.t-systems-mms.cwa/domain"
+	"com.t-systems-mms.cwa/repositories"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidImportProfile = core.ApplicationError("invalid import profile")
+)
+
+type ImportProfiles interface {
+	// GetProfile returns the profile with the given name. If the name is empty,
+	// the profile of the current operator or the DefaultImportProfile is returned.
+	GetProfile(ctx context.Context, name string) (domain.ImportProfile, error)
+
+	// Save validates and saves the profile. All required fields must be mapped to a column
+	// and only known fields may be mapped.
+	Save(ctx context.Context, profile *domain.ImportProfile) error
+}
+
+type importProfilesService struct {
+	profilesRepository repositories.ImportProfiles
+	operatorsService   Operators
+	validate           *validator.Validate
+}
+
+func NewImportProfilesService(profilesRepository repositories.ImportProfiles, operatorsService Operators) ImportProfiles {
+	validate := validator.New()
+	validate.RegisterTagNameFunc(util.JsonTagNameFunc)
+
+	return &importProfilesService{
+		profilesRepository: profilesRepository,
+		operatorsService:   operatorsService,
+		validate:           validate,
+	}
+}
+
+func (s *importProfilesService) GetProfile(ctx context.Context, name string) (domain.ImportProfile, error) {
+	if name != "" {
+		return s.profilesRepository.FindByName(ctx, name)
+	}
+
+	operator, err := s.operatorsService.GetCurrentOperator(ctx)
+	if err != nil {
+		return domain.ImportProfile{}, err
+	}
+
+	if operator.ImportProfileUUID != nil {
+		return s.profilesRepository.FindByUUID(ctx, *operator.ImportProfileUUID)
+	}
+	return DefaultImportProfile, nil
+}
+
+func (s *importProfilesService) Save(ctx context.Context, profile *domain.ImportProfile) error {
+	if err := s.validate.Struct(profile); err != nil {
+		return err
+	}
+
+	for field := range profile.Columns {
+		if !util.ArrayContainsOne(domain.ImportFields, field) {
+			return fmt.Errorf("%w: unknown field %s", ErrInvalidImportProfile, field)
+		}
+	}
+
+	for _, field := range domain.RequiredImportFields {
+		if len(profile.Columns[field]) == 0 {
+			return fmt.Errorf("%w: missing column of required field %s", ErrInvalidImportProfile, field)
+		}
+	}
+
+	if existing, err := s.profilesRepository.FindByName(ctx, profile.Name); err == nil {
+		if existing.UUID != profile.UUID {
+			return fmt.Errorf("%w: duplicate name %s", ErrInvalidImportProfile, profile.Name)
+		}
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	return s.profilesRepository.Save(ctx, profile)
+}
@@ -0,0 +1,120 @@
+/*
+ *   Corona-Warn-App / cwa-map-backend
+ *
+ *   (C) 2020, T-Systems International GmbH
+ *
+ *   Deutsche Telekom AG and all other contributors /
+ *   copyright owners license this file to you under the Apache
+ *   License, Version 2.0 (the "License"); you may not use this
+ *   file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing,
+ *   software distributed under the License is distributed on an
+ *   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *   KIND, either express or implied.  See the License for the
+ *   specific language governing permissions and limitations
+ *   under the License.
+ */
+
+package services
+
+import (
+	"com.t-systems-mms.cwa/domain"
+	"com.t-systems-mms.cwa/external/geocoding"
+	"context"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"regexp"
+	"strings"
+)
+
+// postalCodePattern matches a german postal code within an address
+var postalCodePattern = regexp.MustCompile(`\b(\d{5})\b`)
+
+type PlausibilityConfig struct {
+	// Country is the country (ISO 3166-1 alpha-2) the centers have to be located in.
+	// The outline of the country must be known, see geocoding.HasCountryOutline.
+	Country string
+
+	// MaxPostalCodeDistance is the maximum distance (in km) of a center to the centroid of its postal code.
+	// The distance is only checked, if the postal code dataset has been loaded.
+	MaxPostalCodeDistance float64
+}
+
+// Plausibility checks the coordinates of centers against their address and the configured country
+type Plausibility interface {
+	// Check returns warnings for implausible coordinates of the center.
+	// Centers without coordinates are only reported, if their geocoding has been finished.
+	Check(ctx context.Context, center *domain.Center) []string
+}
+
+type plausibility struct {
+	config      PlausibilityConfig
+	postalCodes geocoding.Geocoder
+}
+
+// NewPlausibility creates the checks using the given geocoder for resolving postal codes,
+// which should not call any remote provider. The distance to the postal code is not checked, if it is nil.
+// The checks which cannot run with the given configuration are logged.
+func NewPlausibility(config PlausibilityConfig, postalCodes geocoding.Geocoder) Plausibility {
+	if !geocoding.HasCountryOutline(config.Country) {
+		logrus.WithField("country", config.Country).
+			Warn("Unknown country outline, skipping plausibility checks of the country")
+	}
+	if postalCodes == nil {
+		logrus.Warn("Postal codes not loaded, skipping plausibility checks of the postal code distance")
+	}
+
+	return &plausibility{
+		config:      config,
+		postalCodes: postalCodes,
+	}
+}
+
+func (p *plausibility) Check(ctx context.Context, center *domain.Center) []string {
+	coordinates := center.Coordinates
+	if coordinates.Latitude == 0 && coordinates.Longitude == 0 {
+		if center.GeocodingStatus != nil && *center.GeocodingStatus != domain.GeocodingPending {
+			return []string{"missing coordinates"}
+		}
+		return nil
+	}
+
+	if inside, known := geocoding.CountryContains(p.config.Country, coordinates); known && !inside {
+		swapped := domain.Coordinates{Latitude: coordinates.Longitude, Longitude: coordinates.Latitude}
+		if swappedInside, _ := geocoding.CountryContains(p.config.Country, swapped); swappedInside {
+			return []string{"latitude and longitude seem to be swapped"}
+		}
+		return []string{fmt.Sprintf("coordinates are outside of %s", strings.ToUpper(p.config.Country))}
+	}
+
+	zip := p.getPostalCode(center)
+	if zip == "" || p.postalCodes == nil {
+		return nil
+	}
+
+	result, err := p.postalCodes.GetCoordinates(ctx, zip)
+	if err != nil {
+		return nil
+	}
+
+	if distance := result.Coordinates.DistanceTo(coordinates); distance > p.config.MaxPostalCodeDistance {
+		return []string{fmt.Sprintf("coordinates are %.0f km away from postal code %s", distance, zip)}
+	}
+	return nil
+}
+
+// getPostalCode returns the last postal code of the address or the postal code of the geocoding result
+func (*plausibility) getPostalCode(center *domain.Center) string {
+	if matches := postalCodePattern.FindAllString(center.Address, -1); len(matches) > 0 {
+		return matches[len(matches)-1]
+	}
+
+	if center.Zip != nil {
+		return *center.Zip
+	}
+	return ""
+}
@@ -0,0 +1,633 @@
+/*
+ *   Corona-Warn-App / cwa-map-backend
+ *
+ *   (C) 2020, T-Systems International GmbH
+ *
+ *   Deutsche Telekom AG and all other contributors /
+ *   copyright owners license this file to you under the Apache
+ *   License, Version 2.0 (the "License"); you may not use this
+ *   file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing,
+ *   software distributed under the License is distributed on an
+ *   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *   KIND, either express or implied.  See the License for the
+ *   specific language governing permissions and limitations
+ *   under the License.
+ */
+
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"com.t-systems-mms.cwa/core"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// maxSpreadsheetRows is the maximum count of rows read from a sheet
+	maxSpreadsheetRows = 100000
+
+	// maxSpreadsheetColumns is the maximum count of columns read from a row
+	maxSpreadsheetColumns = 256
+
+	// maxSpreadsheetFileSize is the maximum uncompressed size (in bytes) of a file read from a spreadsheet archive
+	maxSpreadsheetFileSize = 50 << 20
+)
+
+var (
+	ErrInvalidSpreadsheet = core.ApplicationError("invalid spreadsheet")
+	ErrUnknownSheet       = core.ApplicationError("unknown sheet")
+)
+
+// IsSpreadsheet reports if the data is a zip archive, like xlsx and ods files
+func IsSpreadsheet(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("PK\x03\x04"))
+}
+
+// ParseSpreadsheet parses a xlsx or ods file. The sheet is selected by its name or its index (starting at 1),
+// the first sheet is used if sheet is empty. Dates are converted to the first date format of the profile.
+func (c *CsvParser) ParseSpreadsheet(data []byte, sheet string) ([]ImportCenterResult, ImportSummary, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, ImportSummary{}, fmt.Errorf("%w: %s", ErrInvalidSpreadsheet, err.Error())
+	}
+
+	var rows [][]string
+	if findZipFile(archive, "xl/workbook.xml") != nil {
+		rows, err = c.readXLSX(archive, sheet)
+	} else if findZipFile(archive, "content.xml") != nil {
+		rows, err = c.readODS(archive, sheet)
+	} else {
+		return nil, ImportSummary{}, fmt.Errorf("%w: unsupported file format", ErrInvalidSpreadsheet)
+	}
+
+	if err != nil {
+		return nil, ImportSummary{}, err
+	}
+	return c.parseRows(&spreadsheetRowReader{rows: rows})
+}
+
+// spreadsheetRowReader returns the rows read from a spreadsheet
+type spreadsheetRowReader struct {
+	rows  [][]string
+	index int
+}
+
+func (r *spreadsheetRowReader) Read() ([]string, error) {
+	if r.index >= len(r.rows) {
+		return nil, io.EOF
+	}
+	r.index++
+	return r.rows[r.index-1], nil
+}
+
+func (r *spreadsheetRowReader) Line() int {
+	return r.index
+}
+
+// selectSheet returns the index of the sheet with the given name or index (starting at 1)
+func selectSheet(names []string, sheet string) (int, error) {
+	if sheet == "" && len(names) > 0 {
+		return 0, nil
+	}
+
+	for i, name := range names {
+		if strings.EqualFold(name, sheet) {
+			return i, nil
+		}
+	}
+
+	if index, err := strconv.Atoi(sheet); err == nil && index >= 1 && index <= len(names) {
+		return index - 1, nil
+	}
+	return 0, fmt.Errorf("%w: %s", ErrUnknownSheet, sheet)
+}
+
+// formatDate formats a date of a spreadsheet using the first date format of the profile
+func (c *CsvParser) formatDate(date time.Time) string {
+	if len(c.profile.DateFormats) == 0 {
+		return date.Format("2006-01-02")
+	}
+	return date.Format(c.profile.DateFormats[0])
+}
+
+// formatBool formats a boolean of a spreadsheet using the first true value of the profile
+func (c *CsvParser) formatBool(value bool) string {
+	if !value {
+		return ""
+	} else if len(c.profile.TrueValues) == 0 {
+		return "true"
+	}
+	return c.profile.TrueValues[0]
+}
+
+// appendCell appends the value at the given column index, filling the gap with empty values
+func appendCell(row []string, column int, value string) []string {
+	if column >= maxSpreadsheetColumns {
+		return row
+	}
+	for len(row) < column {
+		row = append(row, "")
+	}
+	return append(row, value)
+}
+
+func findZipFile(archive *zip.Reader, name string) *zip.File {
+	for _, file := range archive.File {
+		if file.Name == name {
+			return file
+		}
+	}
+	return nil
+}
+
+// openZipFile opens the file of the archive. Reading fails, if the uncompressed data exceeds maxSpreadsheetFileSize.
+func openZipFile(file *zip.File) (io.ReadCloser, error) {
+	reader, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidSpreadsheet, err.Error())
+	}
+	return &limitedZipFile{
+		ReadCloser: reader,
+		name:       file.Name,
+		reader:     &io.LimitedReader{R: reader, N: maxSpreadsheetFileSize + 1},
+	}, nil
+}
+
+// limitedZipFile reads at most maxSpreadsheetFileSize bytes of a file within a spreadsheet archive
+type limitedZipFile struct {
+	io.ReadCloser
+	name   string
+	reader *io.LimitedReader
+}
+
+func (f *limitedZipFile) Read(p []byte) (int, error) {
+	n, err := f.reader.Read(p)
+	if f.reader.N <= 0 {
+		return n, fmt.Errorf("%s exceeds %d bytes", f.name, maxSpreadsheetFileSize)
+	}
+	return n, err
+}
+
+// decodeZipFile decodes the xml file of the archive into target, missing files are only reported if required
+func decodeZipFile(archive *zip.Reader, name string, target interface{}, required bool) error {
+	file := findZipFile(archive, name)
+	if file == nil {
+		if required {
+			return fmt.Errorf("%w: missing %s", ErrInvalidSpreadsheet, name)
+		}
+		return nil
+	}
+
+	reader, err := openZipFile(file)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	if err := xml.NewDecoder(reader).Decode(target); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidSpreadsheet, err.Error())
+	}
+	return nil
+}
+
+type xlsxWorkbook struct {
+	Properties struct {
+		Date1904 bool `xml:"date1904,attr"`
+	} `xml:"workbookPr"`
+	Sheets []struct {
+		Name  string     `xml:"name,attr"`
+		Attrs []xml.Attr `xml:",any,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	if len(t.Runs) == 0 {
+		return t.Text
+	}
+
+	var text strings.Builder
+	for _, run := range t.Runs {
+		text.WriteString(run.Text)
+	}
+	return text.String()
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxText `xml:"si"`
+}
+
+type xlsxStyles struct {
+	NumberFormats []struct {
+		ID   int    `xml:"numFmtId,attr"`
+		Code string `xml:"formatCode,attr"`
+	} `xml:"numFmts>numFmt"`
+	CellFormats []struct {
+		NumberFormatID int `xml:"numFmtId,attr"`
+	} `xml:"cellXfs>xf"`
+}
+
+type xlsxRow struct {
+	Index int `xml:"r,attr"`
+	Cells []struct {
+		Ref    string   `xml:"r,attr"`
+		Type   string   `xml:"t,attr"`
+		Style  int      `xml:"s,attr"`
+		Value  string   `xml:"v"`
+		Inline xlsxText `xml:"is"`
+	} `xml:"c"`
+}
+
+// readXLSX reads the rows of the selected sheet of an Office Open XML workbook
+func (c *CsvParser) readXLSX(archive *zip.Reader, sheet string) ([][]string, error) {
+	var workbook xlsxWorkbook
+	if err := decodeZipFile(archive, "xl/workbook.xml", &workbook, true); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(workbook.Sheets))
+	for i, s := range workbook.Sheets {
+		names[i] = s.Name
+	}
+	index, err := selectSheet(names, sheet)
+	if err != nil {
+		return nil, err
+	}
+
+	sheetPath, err := getXLSXSheetPath(archive, workbook.Sheets[index].Attrs)
+	if err != nil {
+		return nil, err
+	}
+
+	var sharedStrings xlsxSharedStrings
+	if err := decodeZipFile(archive, "xl/sharedStrings.xml", &sharedStrings, false); err != nil {
+		return nil, err
+	}
+
+	var styles xlsxStyles
+	if err := decodeZipFile(archive, "xl/styles.xml", &styles, false); err != nil {
+		return nil, err
+	}
+	dateStyles := getXLSXDateStyles(styles)
+
+	file := findZipFile(archive, sheetPath)
+	if file == nil {
+		return nil, fmt.Errorf("%w: missing %s", ErrInvalidSpreadsheet, sheetPath)
+	}
+	reader, err := openZipFile(file)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	rows := make([][]string, 0)
+	decoder := xml.NewDecoder(reader)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidSpreadsheet, err.Error())
+		}
+
+		start, isStart := token.(xml.StartElement)
+		if !isStart || start.Name.Local != "row" {
+			continue
+		}
+
+		var row xlsxRow
+		if err := decoder.DecodeElement(&row, &start); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidSpreadsheet, err.Error())
+		}
+
+		// rows without cells are missing, the index starts at 1
+		if row.Index == 0 {
+			row.Index = len(rows) + 1
+		}
+		if row.Index > maxSpreadsheetRows {
+			return nil, fmt.Errorf("%w: more than %d rows", ErrInvalidSpreadsheet, maxSpreadsheetRows)
+		}
+		for len(rows) < row.Index-1 {
+			rows = append(rows, []string{})
+		}
+
+		values := make([]string, 0, len(row.Cells))
+		for _, cell := range row.Cells {
+			column := len(values)
+			if cell.Ref != "" {
+				column = getXLSXColumn(cell.Ref)
+			}
+
+			value := cell.Value
+			switch cell.Type {
+			case "s":
+				if i, err := strconv.Atoi(cell.Value); err == nil && i >= 0 && i < len(sharedStrings.Items) {
+					value = sharedStrings.Items[i].String()
+				}
+			case "inlineStr":
+				value = cell.Inline.String()
+			case "b":
+				value = c.formatBool(cell.Value == "1")
+			case "d":
+				if date, err := time.Parse("2006-01-02T15:04:05", strings.TrimSuffix(cell.Value, "Z")); err == nil {
+					value = c.formatDate(date)
+				}
+			case "", "n":
+				if cell.Style >= 0 && cell.Style < len(dateStyles) && dateStyles[cell.Style] {
+					if serial, err := strconv.ParseFloat(cell.Value, 64); err == nil {
+						value = c.formatDate(xlsxSerialToTime(serial, workbook.Properties.Date1904))
+					}
+				}
+			}
+			values = appendCell(values, column, value)
+		}
+		rows = append(rows, values)
+	}
+	return rows, nil
+}
+
+// getXLSXSheetPath resolves the path of the sheet using the relationship id of the sheet
+func getXLSXSheetPath(archive *zip.Reader, attrs []xml.Attr) (string, error) {
+	id := ""
+	for _, attr := range attrs {
+		if attr.Name.Local == "id" {
+			id = attr.Value
+		}
+	}
+
+	var relationships xlsxRelationships
+	if err := decodeZipFile(archive, "xl/_rels/workbook.xml.rels", &relationships, true); err != nil {
+		return "", err
+	}
+
+	for _, relationship := range relationships.Relationships {
+		if relationship.ID == id {
+			if strings.HasPrefix(relationship.Target, "/") {
+				return strings.TrimPrefix(relationship.Target, "/"), nil
+			}
+			return path.Join("xl", relationship.Target), nil
+		}
+	}
+	return "", fmt.Errorf("%w: missing sheet %s", ErrInvalidSpreadsheet, id)
+}
+
+// getXLSXDateStyles reports for each cell style if it formats numbers as dates
+func getXLSXDateStyles(styles xlsxStyles) []bool {
+	customFormats := make(map[int]string)
+	for _, format := range styles.NumberFormats {
+		customFormats[format.ID] = format.Code
+	}
+
+	dateStyles := make([]bool, len(styles.CellFormats))
+	for i, format := range styles.CellFormats {
+		id := format.NumberFormatID
+		if code, isCustom := customFormats[id]; isCustom {
+			dateStyles[i] = isDateFormatCode(code)
+		} else {
+			// built-in date formats
+			dateStyles[i] = (id >= 14 && id <= 17) || id == 22
+		}
+	}
+	return dateStyles
+}
+
+// isDateFormatCode reports if the number format contains days or years, ignoring literal text and colors
+func isDateFormatCode(code string) bool {
+	var plain strings.Builder
+	inQuotes, inBrackets := false, false
+	for _, r := range strings.ToLower(code) {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+		case inQuotes:
+		case r == '[':
+			inBrackets = true
+		case r == ']':
+			inBrackets = false
+		case !inBrackets:
+			plain.WriteRune(r)
+		}
+	}
+	return strings.ContainsAny(plain.String(), "dy")
+}
+
+// getXLSXColumn returns the column index (starting at 0) of a cell reference like AB12
+func getXLSXColumn(ref string) int {
+	column := 0
+	for _, r := range strings.ToUpper(ref) {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		column = column*26 + int(r-'A'+1)
+	}
+	return column - 1
+}
+
+// xlsxSerialToTime converts the serial date number of a workbook
+func xlsxSerialToTime(serial float64, date1904 bool) time.Time {
+	base := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	if date1904 {
+		base = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	days := math.Floor(serial)
+	seconds := math.Round((serial - days) * 24 * 60 * 60)
+	return base.AddDate(0, 0, int(days)).Add(time.Duration(seconds) * time.Second)
+}
+
+// odsTable is a sheet of an OpenDocument spreadsheet
+type odsTable struct {
+	name string
+	rows [][]string
+}
+
+// readODS reads the rows of the selected sheet of an OpenDocument spreadsheet.
+// Repeated empty rows and cells are only added, if they are followed by a non-empty row or cell.
+func (c *CsvParser) readODS(archive *zip.Reader, sheet string) ([][]string, error) {
+	file := findZipFile(archive, "content.xml")
+	reader, err := openZipFile(file)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	tables := make([]odsTable, 0)
+	var table *odsTable
+	var row []string
+	var cell strings.Builder
+	var cellAttrs map[string]string
+	inCell := false
+	paragraphs, emptyRows, emptyCells, rowRepeat, cellRepeat := 0, 0, 0, 1, 1
+
+	decoder := xml.NewDecoder(reader)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidSpreadsheet, err.Error())
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			attrs := make(map[string]string)
+			for _, attr := range t.Attr {
+				attrs[attr.Name.Local] = attr.Value
+			}
+
+			switch t.Name.Local {
+			case "table":
+				tables = append(tables, odsTable{name: attrs["name"], rows: make([][]string, 0)})
+				table = &tables[len(tables)-1]
+				emptyRows = 0
+			case "table-row":
+				row = make([]string, 0)
+				rowRepeat = getODSRepeat(attrs["number-rows-repeated"])
+				emptyCells = 0
+			case "table-cell", "covered-table-cell":
+				cell.Reset()
+				cellAttrs = attrs
+				cellRepeat = getODSRepeat(attrs["number-columns-repeated"])
+				inCell = true
+				paragraphs = 0
+			case "annotation":
+				// comments of a cell
+				if err := decoder.Skip(); err != nil {
+					return nil, fmt.Errorf("%w: %s", ErrInvalidSpreadsheet, err.Error())
+				}
+			case "p":
+				if inCell && paragraphs > 0 {
+					cell.WriteString("\n")
+				}
+				paragraphs++
+			case "s":
+				if inCell {
+					cell.WriteString(strings.Repeat(" ", getODSRepeat(attrs["c"])))
+				}
+			case "tab":
+				if inCell {
+					cell.WriteString("\t")
+				}
+			case "line-break":
+				if inCell {
+					cell.WriteString("\n")
+				}
+			}
+		case xml.CharData:
+			if inCell {
+				cell.Write(t)
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "table-cell", "covered-table-cell":
+				inCell = false
+				value := c.getODSCellValue(cellAttrs, cell.String())
+				if value == "" {
+					emptyCells += cellRepeat
+					continue
+				}
+
+				for ; emptyCells > 0 && len(row) < maxSpreadsheetColumns; emptyCells-- {
+					row = append(row, "")
+				}
+				emptyCells = 0
+				for i := 0; i < cellRepeat && len(row) < maxSpreadsheetColumns; i++ {
+					row = append(row, value)
+				}
+			case "table-row":
+				if table == nil {
+					continue
+				}
+				if len(row) == 0 {
+					// trailing empty rows are ignored, so only the exceeded limit has to be kept
+					if emptyRows += rowRepeat; emptyRows > maxSpreadsheetRows {
+						emptyRows = maxSpreadsheetRows + 1
+					}
+					continue
+				}
+
+				if emptyRows > maxSpreadsheetRows || rowRepeat > maxSpreadsheetRows ||
+					len(table.rows)+emptyRows+rowRepeat > maxSpreadsheetRows {
+					return nil, fmt.Errorf("%w: more than %d rows", ErrInvalidSpreadsheet, maxSpreadsheetRows)
+				}
+				for ; emptyRows > 0; emptyRows-- {
+					table.rows = append(table.rows, []string{})
+				}
+				for i := 0; i < rowRepeat; i++ {
+					table.rows = append(table.rows, row)
+				}
+			case "table":
+				table = nil
+			}
+		}
+	}
+
+	names := make([]string, len(tables))
+	for i, t := range tables {
+		names[i] = t.name
+	}
+	index, err := selectSheet(names, sheet)
+	if err != nil {
+		return nil, err
+	}
+	return tables[index].rows, nil
+}
+
+// getODSCellValue returns the value of a cell, using the typed value for dates, numbers and booleans
+func (c *CsvParser) getODSCellValue(attrs map[string]string, text string) string {
+	switch attrs["value-type"] {
+	case "date":
+		value := attrs["date-value"]
+		if len(value) > len("2006-01-02") {
+			value = value[:len("2006-01-02")]
+		}
+		if date, err := time.Parse("2006-01-02", value); err == nil {
+			return c.formatDate(date)
+		}
+	case "float", "percentage", "currency":
+		if value, found := attrs["value"]; found {
+			return value
+		}
+	case "boolean":
+		return c.formatBool(attrs["boolean-value"] == "true")
+	}
+	return text
+}
+
+// getODSRepeat returns the repeat count of a row, cell or space, at most the maximum count of rows plus one,
+// so the limits are still exceeded, but sums of repeat counts cannot overflow
+func getODSRepeat(value string) int {
+	if repeat, err := strconv.Atoi(value); err == nil && repeat > 0 {
+		if repeat > maxSpreadsheetRows {
+			return maxSpreadsheetRows + 1
+		}
+		return repeat
+	}
+	return 1
+}
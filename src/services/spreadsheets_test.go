@@ -0,0 +1,170 @@
+/*
+ *   Corona-Warn-App / cwa-map-backend
+ *
+ *   (C) 2020, T-Systems International GmbH
+ *
+ *   Deutsche Telekom AG and all other contributors /
+ *   copyright owners license this file to you under the Apache
+ *   License, Version 2.0 (the "License"); you may not use this
+ *   file except in compliance with the License.
+ *   You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *   Unless required by applicable law or agreed to in writing,
+ *   software distributed under the License is distributed on an
+ *   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ *   KIND, either express or implied.  See the License for the
+ *   specific language governing permissions and limitations
+ *   under the License.
+ */
+
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"com.t-systems-mms.cwa/domain"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+var spreadsheetTestProfile = domain.ImportProfile{
+	Name:        "test",
+	Delimiter:   ";",
+	HeaderRows:  1,
+	DateFormats: []string{"02.01.2006"},
+	TrueValues:  []string{"ja"},
+	Columns: domain.ImportColumns{
+		domain.ImportFieldName:      {"Name"},
+		domain.ImportFieldStreet:    {"Straße"},
+		domain.ImportFieldZip:       {"PLZ"},
+		domain.ImportFieldCity:      {"Ort"},
+		domain.ImportFieldEmail:     {"E-Mail"},
+		domain.ImportFieldEnterDate: {"Eintrittsdatum"},
+		domain.ImportFieldDCC:       {"DCC"},
+	},
+}
+
+func TestParseSpreadsheet(t *testing.T) {
+	for _, file := range []string{"centers.xlsx", "centers.ods"} {
+		t.Run(file, func(t *testing.T) {
+			data, err := os.ReadFile("testdata/" + file)
+			require.NoError(t, err)
+			require.True(t, IsSpreadsheet(data))
+
+			// the first sheet contains only a note
+			results, summary, err := NewCsvParser(spreadsheetTestProfile).ParseSpreadsheet(data, "teststellen")
+			require.NoError(t, err)
+			assert.Equal(t, ImportSummary{Rows: 3, Empty: 1, Valid: 2}, summary)
+			require.Len(t, results, 2)
+
+			for _, result := range results {
+				assert.Empty(t, result.Errors)
+				assert.Empty(t, result.Warnings)
+				assert.False(t, result.Skipped)
+			}
+
+			center := results[0].Center
+			assert.Equal(t, 2, results[0].Line)
+			assert.Equal(t, "Testzentrum Mitte", center.Name)
+			assert.Equal(t, "Invalidenstr. 1, 10115 Berlin", center.Address)
+			assert.Equal(t, "mitte@example.com", *center.Email)
+			assert.Equal(t, time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), center.EnterDate.UTC())
+			assert.True(t, *center.DCC)
+
+			// formatted text and empty rows
+			center = results[1].Center
+			assert.Equal(t, 4, results[1].Line)
+			assert.Equal(t, "Teststelle Süd", center.Name)
+			assert.Equal(t, "Marienplatz 1, 80331 München", center.Address)
+			assert.Equal(t, time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC), center.EnterDate.UTC())
+			assert.False(t, *center.DCC)
+
+			// sheets can also be selected by their index
+			indexResults, _, err := NewCsvParser(spreadsheetTestProfile).ParseSpreadsheet(data, "2")
+			require.NoError(t, err)
+			assert.Equal(t, results, indexResults)
+
+			_, _, err = NewCsvParser(spreadsheetTestProfile).ParseSpreadsheet(data, "3")
+			assert.True(t, errors.Is(err, ErrUnknownSheet))
+		})
+	}
+}
+
+func TestParseSpreadsheetInvalidFile(t *testing.T) {
+	_, _, err := NewCsvParser(spreadsheetTestProfile).ParseSpreadsheet([]byte("PK\x03\x04invalid"), "")
+	assert.True(t, errors.Is(err, ErrInvalidSpreadsheet))
+
+	var buffer bytes.Buffer
+	archive := zip.NewWriter(&buffer)
+	_, err = archive.Create("document.xml")
+	require.NoError(t, err)
+	require.NoError(t, archive.Close())
+
+	_, _, err = NewCsvParser(spreadsheetTestProfile).ParseSpreadsheet(buffer.Bytes(), "")
+	assert.True(t, errors.Is(err, ErrInvalidSpreadsheet))
+}
+
+func TestParseSpreadsheetMaxFileSize(t *testing.T) {
+	// highly compressible content, which exceeds the limit after decompressing it
+	var buffer bytes.Buffer
+	archive := zip.NewWriter(&buffer)
+	writer, err := archive.Create("content.xml")
+	require.NoError(t, err)
+	_, err = writer.Write([]byte("<?xml version=\"1.0\"?><document-content>"))
+	require.NoError(t, err)
+	padding := []byte(strings.Repeat(" ", 1<<20))
+	for i := 0; i <= maxSpreadsheetFileSize>>20; i++ {
+		_, err = writer.Write(padding)
+		require.NoError(t, err)
+	}
+	_, err = writer.Write([]byte("</document-content>"))
+	require.NoError(t, err)
+	require.NoError(t, archive.Close())
+	require.Less(t, buffer.Len(), 1<<20)
+
+	_, _, err = NewCsvParser(spreadsheetTestProfile).ParseSpreadsheet(buffer.Bytes(), "")
+	assert.True(t, errors.Is(err, ErrInvalidSpreadsheet))
+	assert.Contains(t, err.Error(), "content.xml exceeds")
+}
+
+func TestParseSpreadsheetMaxRows(t *testing.T) {
+	tests := []struct {
+		name string
+		rows string
+	}{
+		{"repeated row", `<table:table-row table:number-rows-repeated="100001"><table:table-cell><text:p>x</text:p></table:table-cell></table:table-row>`},
+		{"overflowing repeat", `<table:table-row><table:table-cell/></table:table-row>` +
+			`<table:table-row table:number-rows-repeated="9223372036854775807"><table:table-cell><text:p>x</text:p></table:table-cell></table:table-row>`},
+		{"repeated empty rows", `<table:table-row table:number-rows-repeated="9223372036854775807"><table:table-cell/></table:table-row>` +
+			`<table:table-row table:number-rows-repeated="9223372036854775807"><table:table-cell/></table:table-row>` +
+			`<table:table-row><table:table-cell><text:p>x</text:p></table:table-cell></table:table-row>`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buffer bytes.Buffer
+			archive := zip.NewWriter(&buffer)
+			writer, err := archive.Create("content.xml")
+			require.NoError(t, err)
+			_, err = writer.Write([]byte(`<?xml version="1.0"?>` +
+				`<office:document-content xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0" ` +
+				`xmlns:table="urn:oasis:names:tc:opendocument:xmlns:table:1.0" ` +
+				`xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0">` +
+				`<office:body><office:spreadsheet><table:table table:name="Teststellen">` + test.rows +
+				`</table:table></office:spreadsheet></office:body></office:document-content>`))
+			require.NoError(t, err)
+			require.NoError(t, archive.Close())
+
+			_, _, err = NewCsvParser(spreadsheetTestProfile).ParseSpreadsheet(buffer.Bytes(), "")
+			assert.True(t, errors.Is(err, ErrInvalidSpreadsheet))
+			assert.Contains(t, err.Error(), "more than")
+		})
+	}
+}